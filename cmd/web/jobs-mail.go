@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -98,6 +99,26 @@ func (d *Dispatcher) dispatch() {
 	}
 }
 
+// createMailTemplateCache parses every email template in dir, keyed by file name
+func createMailTemplateCache(dir string) (map[string]*template.Template, error) {
+	cache := make(map[string]*template.Template)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return cache, err
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		t, err := template.New(name).ParseFiles(file)
+		if err != nil {
+			return cache, err
+		}
+		cache[name] = t
+	}
+	return cache, nil
+}
+
 // processMailQueueJob processes the main queue job (sends email)
 func (w Worker) processMailQueueJob(mailMessage channeldata.MailData) {
 
@@ -112,6 +133,9 @@ func (w Worker) processMailQueueJob(mailMessage channeldata.MailData) {
 		return
 	}
 
+	// the smtp settings can be saved on the settings page while mail is being sent
+	pm := app.Preferences.Map()
+
 	data := struct {
 		Content       template.HTML
 		From          string
@@ -125,7 +149,7 @@ func (w Worker) processMailQueueJob(mailMessage channeldata.MailData) {
 		Content:       mailMessage.Content,
 		FromName:      mailMessage.FromName,
 		From:          mailMessage.FromAddress,
-		PreferenceMap: preferenceMap,
+		PreferenceMap: pm,
 		IntMap:        mailMessage.IntMap,
 		StringMap:     mailMessage.StringMap,
 		FloatMap:      mailMessage.FloatMap,
@@ -152,19 +176,26 @@ func (w Worker) processMailQueueJob(mailMessage channeldata.MailData) {
 		formattedMessage = result
 	}
 
-	port, _ := strconv.Atoi(preferenceMap["smtp_port"])
+	port, _ := strconv.Atoi(pm["smtp_port"])
 
 	server := mail.NewSMTPClient()
-	server.Host = preferenceMap["smtp_server"]
+	server.Host = pm["smtp_server"]
 	server.Port = port
-	server.Username = preferenceMap["smtp_user"]
-	server.Password = preferenceMap["smtp_password"]
-	if preferenceMap["smtp_server"] == "localhost" {
+	server.Username = pm["smtp_user"]
+	server.Password = pm["smtp_password"]
+	if pm["smtp_server"] == "localhost" {
 		server.Authentication = mail.AuthPlain
 	} else {
 		server.Authentication = mail.AuthLogin
 	}
-	server.Encryption = mail.EncryptionTLS
+	switch pm["smtp_encryption"] {
+	case "none":
+		server.Encryption = mail.EncryptionNone
+	case "ssl":
+		server.Encryption = mail.EncryptionSSL
+	default:
+		server.Encryption = mail.EncryptionTLS
+	}
 	server.KeepAlive = false
 	server.ConnectTimeout = 10 * time.Second
 	server.SendTimeout = 10 * time.Second
@@ -172,6 +203,7 @@ func (w Worker) processMailQueueJob(mailMessage channeldata.MailData) {
 	smtpClient, err := server.Connect()
 	if err != nil {
 		log.Println(err)
+		return
 	}
 
 	email := mail.NewMSG()
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/channeldata"
+	"github.com/brianmaksy/go-watch/internal/config"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/notifiers"
+)
+
+// sentMail is a message received by the smtp sink
+type sentMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpSink accepts mail on 127.0.0.1 without authentication or encryption, and hands
+// every message it receives to the returned channel
+func smtpSink(t *testing.T) (string, int, <-chan sentMail) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	received := make(chan sentMail, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, received)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+// serveSMTP speaks just enough smtp to take messages on one connection
+func serveSMTP(conn net.Conn, received chan<- sentMail) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	_ = tp.PrintfLine("220 sink ESMTP")
+	var m sentMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			_ = tp.PrintfLine("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			m = sentMail{From: strings.Trim(line[len("MAIL FROM:"):], " <>")}
+			_ = tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			m.To = append(m.To, strings.Trim(line[len("RCPT TO:"):], " <>"))
+			_ = tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.Data = string(data)
+			received <- m
+			_ = tp.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+// startMailer points the mail dispatcher at the smtp sink, as setupApp does for a real
+// server
+func startMailer(t *testing.T) <-chan sentMail {
+	t.Helper()
+
+	host, port, received := smtpSink(t)
+
+	templateCache, err := createMailTemplateCache("../../email-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.TemplateCache = templateCache
+
+	app.Preferences = config.NewPreferences(map[string]string{
+		"smtp_server":     host,
+		"smtp_port":       strconv.Itoa(port),
+		"smtp_encryption": "none",
+		"smtp_from_email": "go-watch@example.test",
+		"smtp_from_name":  "go_watch",
+	})
+
+	app.MailQueue = make(chan channeldata.MailJob, maxWorkerPoolSize)
+	helpers.NewHelpers(&app)
+	NewDispatcher(app.MailQueue, maxJobMaxWorkers).run()
+
+	return received
+}
+
+// nextMail waits for the sink to receive a message
+func nextMail(t *testing.T, received <-chan sentMail) sentMail {
+	t.Helper()
+
+	select {
+	case m := <-received:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+	return sentMail{}
+}
+
+func TestEmailNotification(t *testing.T) {
+	received := startMailer(t)
+	email := notifiers.EmailNotifier{Send: helpers.SendEmail}
+
+	n := notifiers.Notification{
+		HostServiceID: 1,
+		HostName:      "web1",
+		ServiceName:   "HTTP",
+		OldStatus:     "healthy",
+		NewStatus:     "problem",
+		Message:       "http://web1 - 500 Internal Server Error",
+		Link:          "http://go-watch.test/admin/host/1",
+		Time:          time.Now(),
+	}
+	pm := map[string]string{
+		"notify_via_email": "1",
+		"notify_name":      "Ops",
+		"notify_email":     "ops@example.test",
+	}
+
+	t.Run("notify address", func(t *testing.T) {
+		if err := email.Notify(pm, n); err != nil {
+			t.Fatal(err)
+		}
+
+		m := nextMail(t, received)
+		if m.From != "go-watch@example.test" {
+			t.Errorf("sent from %q", m.From)
+		}
+		if len(m.To) != 1 || m.To[0] != "ops@example.test" {
+			t.Errorf("sent to %v", m.To)
+		}
+		for _, want := range []string{"Subject: HTTP on web1 is problem", "500 Internal Server Error", n.Link} {
+			if !strings.Contains(m.Data, want) {
+				t.Errorf("message does not contain %q:\n%s", want, m.Data)
+			}
+		}
+	})
+
+	t.Run("on call", func(t *testing.T) {
+		oc := n
+		oc.OnCall = []notifiers.Recipient{{Name: "Ann", Email: "ann@example.test"}}
+		if err := email.Notify(pm, oc); err != nil {
+			t.Fatal(err)
+		}
+
+		m := nextMail(t, received)
+		if len(m.To) != 1 || m.To[0] != "ann@example.test" {
+			t.Errorf("sent to %v, want the on call user only", m.To)
+		}
+	})
+
+	t.Run("first healthy check", func(t *testing.T) {
+		first := n
+		first.OldStatus, first.NewStatus = "pending", "healthy"
+		if err := email.Notify(pm, first); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case m := <-received:
+			t.Errorf("sent mail to %v for a service coming up healthy", m.To)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+}
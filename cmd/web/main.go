@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/gob"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 	"time"
+	_ "time/tzdata" // on-call schedule time zones must load even where the OS has no zone database
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/brianmaksy/go-watch/internal/config"
@@ -18,13 +22,16 @@ import (
 var app config.AppConfig
 var repo *handlers.DBRepo
 var session *scs.SessionManager
-var preferenceMap map[string]string
 var wsClient pusher.Client
 
 const go_watchVersion = "1.0.0"
 const maxWorkerPoolSize = 5
 const maxJobMaxWorkers = 5
 
+// leaseTTL is how long the scheduler lease lasts unless renewed, and so about how long
+// a standby instance waits before taking over from a leader that has gone away
+const leaseTTL = 30 * time.Second
+
 func init() {
 	// type that holds admin users
 	gob.Register(models.User{})
@@ -64,9 +71,19 @@ func main() {
 
 	log.Printf("Starting HTTP server on port %s....", *insecurePort)
 
+	// on shutdown, give up the lead so a standby takes over straight away
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		log.Println("Shutting down....")
+		app.Leader.Stop()
+		_ = srv.Shutdown(context.Background())
+	}()
+
 	// start the server
 	err = srv.ListenAndServe()
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
 }
@@ -66,7 +66,7 @@ func NoSurf(next http.Handler) http.Handler {
 func CheckRemember(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !helpers.IsAuthenticated(r) {
-			cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", preferenceMap["identifier"]))
+			cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", app.Preferences.Get("identifier")))
 			if err != nil {
 				next.ServeHTTP(w, r)
 			} else {
@@ -103,7 +103,7 @@ func CheckRemember(next http.Handler) http.Handler {
 			}
 		} else {
 			// they are logged in, but make sure that the remember token has not been revoked
-			cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", preferenceMap["identifier"]))
+			cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", app.Preferences.Get("identifier")))
 			if err != nil {
 				// no cookie
 				next.ServeHTTP(w, r)
@@ -135,7 +135,7 @@ func deleteRememberCookie(w http.ResponseWriter, r *http.Request) {
 	_ = session.RenewToken(r.Context())
 	// delete the cookie
 	newCookie := http.Cookie{
-		Name:     fmt.Sprintf("_%s_ggowatcher_remember", preferenceMap["identifier"]),
+		Name:     fmt.Sprintf("_%s_ggowatcher_remember", app.Preferences.Get("identifier")),
 		Value:    "",
 		Path:     "/",
 		Expires:  time.Now().Add(-100 * time.Hour),
@@ -45,9 +45,28 @@ func routes() http.Handler {
 		// events
 		mux.Get("/events", handlers.Repo.Events)
 
+		// reports
+		mux.Get("/reports", handlers.Repo.UptimeReport)
+		mux.Get("/reports/uptime.csv", handlers.Repo.UptimeReportCSV)
+
 		// settings
 		mux.Get("/settings", handlers.Repo.Settings)
 		mux.Post("/settings", handlers.Repo.PostSettings)
+		mux.Post("/settings/ajax/test-sms", handlers.Repo.SendTestSMS)
+		mux.Post("/settings/ajax/test-chat", handlers.Repo.SendTestChat)
+
+		// maintenance windows
+		mux.Get("/maintenance", handlers.Repo.AllMaintenanceWindows)
+		mux.Get("/maintenance/{id}", handlers.Repo.OneMaintenanceWindow)
+		mux.Post("/maintenance/{id}", handlers.Repo.PostOneMaintenanceWindow)
+		mux.Get("/maintenance/delete/{id}", handlers.Repo.DeleteMaintenanceWindow)
+
+		// webhooks
+		mux.Get("/webhooks", handlers.Repo.AllWebhooks)
+		mux.Get("/webhook/{id}", handlers.Repo.OneWebhook)
+		mux.Post("/webhook/{id}", handlers.Repo.PostOneWebhook)
+		mux.Get("/webhook/delete/{id}", handlers.Repo.DeleteWebhook)
+		mux.Post("/webhook/ajax/test", handlers.Repo.TestWebhook)
 
 		// service status pages (all hosts)
 		mux.Get("/all-healthy", handlers.Repo.AllHealthyServices)
@@ -68,12 +87,41 @@ func routes() http.Handler {
 		mux.Post("/preference/ajax/set-system-pref", handlers.Repo.SetSystemPref)
 		mux.Post("/preference/ajax/toggle-monitoring", handlers.Repo.ToggleMonitoring)
 
+		// incidents
+		mux.Get("/incidents", handlers.Repo.AllIncidents)
+		mux.Get("/incidents/{id}", handlers.Repo.OneIncident)
+		mux.Post("/incidents/{id}/acknowledge", handlers.Repo.AcknowledgeIncident)
+		mux.Post("/incidents/{id}/note", handlers.Repo.PostIncidentNote)
+		mux.Post("/incidents/{id}/resolution", handlers.Repo.PostIncidentResolution)
+
+		// escalation policies
+		mux.Get("/escalation-policies", handlers.Repo.AllEscalationPolicies)
+		mux.Get("/escalation-policy/{id}", handlers.Repo.OneEscalationPolicy)
+		mux.Post("/escalation-policy/{id}", handlers.Repo.PostOneEscalationPolicy)
+		mux.Get("/escalation-policy/delete/{id}", handlers.Repo.DeleteEscalationPolicy)
+
+		// on-call schedules
+		mux.Get("/oncall", handlers.Repo.OnCall)
+		mux.Get("/oncall/{id}", handlers.Repo.OneOnCallSchedule)
+		mux.Post("/oncall/{id}", handlers.Repo.PostOneOnCallSchedule)
+		mux.Get("/oncall/delete/{id}", handlers.Repo.DeleteOnCallSchedule)
+		mux.Post("/oncall/{id}/override", handlers.Repo.PostOnCallOverride)
+		mux.Get("/oncall/{id}/override/delete/{overrideID}", handlers.Repo.DeleteOnCallOverride)
+
+		// services
+		mux.Get("/services", handlers.Repo.AllServices)
+		mux.Post("/services/ajax/toggle", handlers.Repo.ToggleService)
+
 		// hosts
 		mux.Get("/host/all", handlers.Repo.AllHosts)
 		mux.Get("/host/{id}", handlers.Repo.Host)
 		mux.Post("/host/{id}", handlers.Repo.PostHost)
 		mux.Post("/host/ajax/toggle-service", handlers.Repo.ToggleServiceForHost)
+		mux.Post("/host/ajax/service-config", handlers.Repo.SaveHostServiceConfig)
+		mux.Post("/host/ajax/add-dependency", handlers.Repo.AddHostDependency)
+		mux.Post("/host/ajax/remove-dependency", handlers.Repo.RemoveHostDependency)
 		mux.Get("/perform-check/{id}/{oldStatus}", handlers.Repo.TestCheck)
+		mux.Get("/host-service/{id}/results", handlers.Repo.CheckResults)
 
 	})
 
@@ -11,10 +11,15 @@ import (
 	"github.com/alexedwards/scs/postgresstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/brianmaksy/go-watch/internal/channeldata"
+	"github.com/brianmaksy/go-watch/internal/checkers"
 	"github.com/brianmaksy/go-watch/internal/config"
 	"github.com/brianmaksy/go-watch/internal/driver"
+	"github.com/brianmaksy/go-watch/internal/executor"
 	"github.com/brianmaksy/go-watch/internal/handlers"
 	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/leader"
+	"github.com/brianmaksy/go-watch/internal/monitor"
+	"github.com/brianmaksy/go-watch/internal/notifiers"
 	"github.com/pusher/pusher-http-go"
 	"github.com/robfig/cron/v3"
 )
@@ -23,6 +28,7 @@ func setupApp() (*string, error) {
 	// read flags
 	insecurePort := flag.String("port", ":4000", "port to listen on")
 	identifier := flag.String("identifier", "go_watch", "unique identifier")
+	instance := flag.String("instance", "", "name of this instance, when running several (default host name and port)")
 	domain := flag.String("domain", "localhost", "domain name (e.g. example.com)")
 	inProduction := flag.Bool("production", false, "application is in production")
 	dbHost := flag.String("dbhost", "localhost", "database host")
@@ -106,12 +112,22 @@ func setupApp() (*string, error) {
 
 	app = a
 
+	log.Println("Reading email templates....")
+	templateCache, err := createMailTemplateCache("./email-templates")
+	if err != nil {
+		log.Fatal("Cannot read email templates:", err)
+	}
+	app.TemplateCache = templateCache
+
 	repo = handlers.NewPostgresqlHandlers(db, &app) // nts - call this first (one var)
 	handlers.NewHandlers(repo, &app)                // nts - then use the declared repo here.
 	// nts - repo has access to both repository.DatabaseRepo methods and appconfig params.
 
+	// webhooks are stored in the database, so their notifier is registered here rather than in init
+	notifiers.Register(notifiers.NewWebhookNotifier(repo.DB))
+
 	log.Println("Getting preferences...")
-	preferenceMap = make(map[string]string) // [NTS - strictly speaking not needed since declared in main.go already?]
+	preferenceMap := make(map[string]string)
 	preferences, err := repo.DB.AllPreferences()
 	if err != nil {
 		log.Fatal("Cannot read preferences:", err)
@@ -127,7 +143,16 @@ func setupApp() (*string, error) {
 	preferenceMap["identifier"] = *identifier
 	preferenceMap["version"] = go_watchVersion
 
-	app.PreferenceMap = preferenceMap
+	app.Preferences = config.NewPreferences(preferenceMap)
+
+	// configure the client used by service checks
+	err = checkers.Configure(checkers.SettingsFromPreferences(preferenceMap))
+	if err != nil {
+		log.Println("Invalid check client settings, using defaults:", err)
+	}
+
+	// scheduled checks run on a pool of workers rather than all at once
+	app.Checks = executor.New(executor.SettingsFromPreferences(preferenceMap), handlers.Repo.ScheduledCheck)
 
 	// create pusher client. The official Go library for pusher. Use local pusher clone instead.
 	wsClient = pusher.Client{
@@ -142,26 +167,30 @@ func setupApp() (*string, error) {
 	log.Println("Secure", *pusherSecure)
 
 	app.WsClient = wsClient
-	// nts - since we can't assign entry to nil map (called in start-monitoring.go)
-	monitorMap := make(map[int]cron.EntryID)
-	app.MonitorMap = monitorMap
-
 	// create a timer - will hold our schedule
 	localZone, _ := time.LoadLocation("Local")
+	// jobs only hand checks to the executor and return at once; the executor skips a
+	// check whose last run is still queued or running, so jobs are not delayed here
 	scheduler := cron.New(cron.WithLocation(localZone), cron.WithChain(
-		cron.DelayIfStillRunning(cron.DefaultLogger),
 		cron.Recover(cron.DefaultLogger),
 	))
 	app.Scheduler = scheduler
-	// NTS - need to run this now. start-monitoring.go
-
-	go handlers.Repo.StartMonitoring()
-
-	// nts - do actually need this here: (to start monitoring when start app)?
-	// otherwise need to turn on and off again.
-	if app.PreferenceMap["monitoring_live"] == "1" {
-		app.Scheduler.Start()
+	// the check jobs of host services are only ever scheduled through the registry
+	app.Monitors = monitor.NewRegistry(scheduler)
+
+	// when several instances share the database, only the one holding the scheduler
+	// lease runs checks; the others stand by to take over if its lease expires.
+	// Becoming the leader starts monitoring (start-monitoring.go) if it is on.
+	name := *instance
+	if name == "" {
+		hostName, _ := os.Hostname()
+		name = hostName + *insecurePort
 	}
+	app.Leader = leader.New(repo.DB, handlers.LeaseName, name, leaseTTL)
+	app.Leader.OnElected = handlers.Repo.LeaderElected
+	app.Leader.OnRenewed = handlers.Repo.LeaderRenewed
+	app.Leader.OnDemoted = handlers.Repo.LeaderDemoted
+	go app.Leader.Run()
 
 	helpers.NewHelpers(&app)
 
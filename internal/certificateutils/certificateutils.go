@@ -2,6 +2,7 @@ package certificateutils
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
@@ -110,7 +111,10 @@ func ReadCertificateDetailsFromFile(publicCertFile, privateCertFile string) ([]C
 	return certDetails, nil
 }
 
-func GetCertificateDetails(hostname string, connectionTimeout int) (CertificateDetails, error) {
+// GetCertificateDetails connects to hostname and returns the details of its certificate.
+// It gives up on the connect after connectTimeout, and on the TLS handshake after
+// handshakeTimeout. A timeout of zero is no limit.
+func GetCertificateDetails(hostname string, connectTimeout, handshakeTimeout time.Duration) (CertificateDetails, error) {
 	currentTime := time.Now()
 	var certDetails CertificateDetails
 
@@ -123,22 +127,26 @@ func GetCertificateDetails(hostname string, connectionTimeout int) (CertificateD
 	}
 
 	// Establish a new TCP connection to hostname
-	// Ignore invalid certificates, so we can scan via IP addresses or hostnames
-	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second * time.Duration(connectionTimeout)},
-		"tcp",
-		hostname,
-		&tls.Config{InsecureSkipVerify: true})
-
+	rawConn, err := (&net.Dialer{Timeout: connectTimeout}).Dial("tcp", hostname)
 	if err != nil {
-		return CertificateDetails{}, fmt.Errorf("Connection error: %v", err)
-	}
-
-	if handshakeCompleted := conn.ConnectionState().HandshakeComplete; !handshakeCompleted {
-		return CertificateDetails{}, fmt.Errorf("TLS Handshake failed to hostname %s.", hostname)
+		return CertificateDetails{}, fmt.Errorf("Connection error: %w", err)
 	}
 
+	// Ignore invalid certificates, so we can scan via IP addresses or hostnames
+	serverName, _, _ := net.SplitHostPort(hostname)
+	conn := tls.Client(rawConn, &tls.Config{InsecureSkipVerify: true, ServerName: serverName})
 	defer conn.Close()
 
+	ctx := context.Background()
+	if handshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, handshakeTimeout)
+		defer cancel()
+	}
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return CertificateDetails{}, fmt.Errorf("TLS Handshake failed to hostname %s: %w", hostname, err)
+	}
+
 	// Loop through each certificate peer and determine certificate details for non-CA certificate
 	for _, cert := range conn.ConnectionState().PeerCertificates {
 
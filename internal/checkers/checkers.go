@@ -0,0 +1,112 @@
+package checkers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// Error classes describe why a check did not come back healthy
+const (
+	ErrorTimeout     = "timeout"
+	ErrorConnect     = "connect"
+	ErrorResolve     = "resolve"
+	ErrorStatus      = "status"
+	ErrorContent     = "content"
+	ErrorLatency     = "latency"
+	ErrorCertificate = "certificate"
+	ErrorConfig      = "config"
+)
+
+// Result holds the outcome of a single check
+type Result struct {
+	Status     string
+	Message    string
+	Latency    time.Duration
+	ErrorClass string // empty when healthy
+}
+
+// Checker is implemented by every kind of service check. Checkers are looked up
+// by the service_type column of the services table, not by service id.
+type Checker interface {
+	Check(h models.Host, hs models.HostService) Result
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Checker)
+)
+
+// Register makes a checker available under a service type name. It panics if the
+// name is empty or already taken, since that is always a programming error.
+func Register(serviceType string, c Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if serviceType == "" {
+		panic("checkers: Register called with empty service type")
+	}
+	if c == nil {
+		panic("checkers: Register called with nil checker for " + serviceType)
+	}
+	if _, exists := registry[serviceType]; exists {
+		panic("checkers: Register called twice for " + serviceType)
+	}
+	registry[serviceType] = c
+}
+
+// Get returns the checker registered for a service type
+func Get(serviceType string) (Checker, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c, ok := registry[serviceType]
+	return c, ok
+}
+
+// Types returns the names of all registered service types, sorted
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var types []string
+	for k := range registry {
+		types = append(types, k)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// timed makes one attempt at a check and records how long it took on the result,
+// whether it failed or not, so failures are not charted as instant
+func timed(attempt func() Result) Result {
+	start := time.Now()
+	res := attempt()
+	res.Latency = time.Since(start)
+	return res
+}
+
+// applyLatencyThresholds downgrades a healthy result to warning or problem when it
+// took longer than the host service's thresholds allow. A threshold of zero is off.
+func applyLatencyThresholds(res Result, cfg models.CheckConfig) Result {
+	if res.Status != "healthy" {
+		return res
+	}
+
+	ms := int(res.Latency / time.Millisecond)
+
+	switch {
+	case cfg.CriticalLatency > 0 && ms >= cfg.CriticalLatency:
+		res.Status = "problem"
+		res.ErrorClass = ErrorLatency
+		res.Message = fmt.Sprintf("%s - slower than %dms", res.Message, cfg.CriticalLatency)
+	case cfg.WarnLatency > 0 && ms >= cfg.WarnLatency:
+		res.Status = "warning"
+		res.ErrorClass = ErrorLatency
+		res.Message = fmt.Sprintf("%s - slower than %dms", res.Message, cfg.WarnLatency)
+	}
+	return res
+}
@@ -0,0 +1,151 @@
+package checkers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ClientSettings controls how checks connect to the things they check
+type ClientSettings struct {
+	ConnectTimeout time.Duration
+	TLSTimeout     time.Duration
+	Timeout        time.Duration // overall limit for a single check request
+	MaxRedirects   int           // 0 means redirects are not followed
+	UserAgent      string
+	Proxy          string // proxy url for http(s) checks, blank for none
+}
+
+// DefaultClientSettings are used until Configure is called, and for any
+// preference that is missing or invalid
+var DefaultClientSettings = ClientSettings{
+	ConnectTimeout: 5 * time.Second,
+	TLSTimeout:     5 * time.Second,
+	Timeout:        15 * time.Second,
+	MaxRedirects:   10,
+	UserAgent:      "go-watch",
+}
+
+var (
+	clientMu   sync.RWMutex
+	settings   = DefaultClientSettings
+	httpClient = newHTTPClient(DefaultClientSettings, nil)
+)
+
+// SettingsFromPreferences builds client settings from the preference map. Timeouts
+// are stored in seconds.
+func SettingsFromPreferences(pm map[string]string) ClientSettings {
+	s := DefaultClientSettings
+
+	seconds := func(name string, def time.Duration) time.Duration {
+		n, err := strconv.Atoi(pm[name])
+		if err != nil || n <= 0 {
+			return def
+		}
+		return time.Duration(n) * time.Second
+	}
+
+	s.ConnectTimeout = seconds("check_connect_timeout", s.ConnectTimeout)
+	s.TLSTimeout = seconds("check_tls_timeout", s.TLSTimeout)
+	s.Timeout = seconds("check_timeout", s.Timeout)
+
+	if n, err := strconv.Atoi(pm["check_max_redirects"]); err == nil && n >= 0 {
+		s.MaxRedirects = n
+	}
+	if pm["check_user_agent"] != "" {
+		s.UserAgent = pm["check_user_agent"]
+	}
+	s.Proxy = pm["check_proxy"]
+
+	return s
+}
+
+// Configure replaces the settings and http client used by checks
+func Configure(s ClientSettings) error {
+	proxy, err := s.proxyURL()
+	if err != nil {
+		return err
+	}
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	settings = s
+	httpClient = newHTTPClient(s, proxy)
+	return nil
+}
+
+// Validate reports whether the settings can be used, without putting them in use
+func (s ClientSettings) Validate() error {
+	_, err := s.proxyURL()
+	return err
+}
+
+// proxyURL parses the proxy url, which is nil if checks are not proxied
+func (s ClientSettings) proxyURL() (*url.URL, error) {
+	if s.Proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(s.Proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url '%s'", s.Proxy)
+	}
+	return u, nil
+}
+
+// currentSettings returns the settings in use
+func currentSettings() ClientSettings {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return settings
+}
+
+// currentHTTPClient returns the http client in use, along with its settings
+func currentHTTPClient() (*http.Client, ClientSettings) {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return httpClient, settings
+}
+
+// newHTTPClient creates the http client for checks. It never reuses connections,
+// so every check measures a fresh connect and handshake.
+func newHTTPClient(s ClientSettings, proxy *url.URL) *http.Client {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: s.ConnectTimeout,
+		}).DialContext,
+		TLSHandshakeTimeout: s.TLSTimeout,
+		DisableKeepAlives:   true,
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   s.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// stop and hand back the redirect response itself, so it is
+			// judged against the accepted status codes
+			if len(via) > s.MaxRedirects {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// isTimeout reports whether err was caused by a timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,184 @@
+package checkers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// DNSRecordTypes are the record types the dns checker knows how to assert on
+var DNSRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT"}
+
+func init() {
+	Register("dns", DNSChecker{})
+}
+
+// DNSChecker resolves the host's canonical name and compares the answer with
+// what we expect. A failed lookup is a problem; an unexpected answer is a warning.
+type DNSChecker struct{}
+
+// Check performs the dns check
+func (c DNSChecker) Check(h models.Host, hs models.HostService) Result {
+	res := timed(func() Result { return c.resolve(h, hs) })
+	return applyLatencyThresholds(res, hs.Config)
+}
+
+// resolve looks up the host's name and compares the answer with what is expected
+func (c DNSChecker) resolve(h models.Host, hs models.HostService) Result {
+	name := h.CanonicalName
+	if name == "" {
+		name = hostAddress(models.Host{URL: h.URL})
+	}
+
+	recordType := strings.ToUpper(hs.Config.DNSRecordType)
+	if recordType == "" {
+		recordType = "A"
+	}
+
+	answers, err := c.lookup(hs.Config.DNSResolver, name, recordType)
+	if err != nil {
+		if isTimeout(err) {
+			return Result{Status: "problem", ErrorClass: ErrorTimeout, Message: fmt.Sprintf("%s %s - timed out", name, recordType)}
+		}
+		return Result{Status: "problem", ErrorClass: ErrorResolve, Message: fmt.Sprintf("%s %s - resolution failed: %s", name, recordType, err)}
+	}
+	if len(answers) == 0 {
+		return Result{Status: "problem", ErrorClass: ErrorResolve, Message: fmt.Sprintf("%s %s - no records returned", name, recordType)}
+	}
+
+	// if nothing explicit is configured, A and AAAA records are compared
+	// with the addresses stored on the host
+	expected := splitList(hs.Config.DNSExpected)
+	if len(expected) == 0 {
+		switch recordType {
+		case "A":
+			expected = splitList(h.IP)
+		case "AAAA":
+			expected = splitList(h.IPV6)
+		}
+	}
+
+	got := strings.Join(answers, ", ")
+	for _, want := range expected {
+		if !answerMatches(recordType, want, answers) {
+			return Result{
+				Status:     "warning",
+				ErrorClass: ErrorContent,
+				Message:    fmt.Sprintf("%s %s - expected %s, got %s", name, recordType, want, got),
+			}
+		}
+	}
+
+	return Result{Status: "healthy", Message: fmt.Sprintf("%s %s - %s", name, recordType, got)}
+}
+
+// lookup resolves name for the given record type and returns the answers as strings
+func (c DNSChecker) lookup(resolverAddress, name, recordType string) ([]string, error) {
+	s := currentSettings()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	resolver := newResolver(resolverAddress, s.ConnectTimeout)
+
+	var answers []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, strings.TrimSuffix(cname, "."))
+
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, strings.TrimSuffix(mx.Host, "."))
+		}
+
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, txts...)
+
+	default:
+		return nil, fmt.Errorf("unsupported record type %s", recordType)
+	}
+
+	return answers, nil
+}
+
+// newResolver returns a resolver that queries address, or the system resolver
+// if address is empty. The port defaults to 53.
+func newResolver(address string, timeout time.Duration) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// answerMatches reports whether want is among the answers. Addresses are compared
+// as IPs, names without case or trailing dot, and TXT records by substring.
+func answerMatches(recordType, want string, answers []string) bool {
+	for _, answer := range answers {
+		switch recordType {
+		case "A", "AAAA":
+			if ip := net.ParseIP(want); ip != nil && ip.Equal(net.ParseIP(answer)) {
+				return true
+			}
+		case "TXT":
+			if strings.Contains(answer, want) {
+				return true
+			}
+		default:
+			if strings.EqualFold(strings.TrimSuffix(want, "."), answer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
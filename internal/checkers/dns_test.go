@@ -0,0 +1,137 @@
+package checkers
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// stubDNS answers A queries over udp on 127.0.0.1 from records, by name with a
+// trailing dot, after waiting for delay, and returns NXDOMAIN for every other name
+func stubDNS(t *testing.T, records map[string][]string, delay time.Duration) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			time.Sleep(delay)
+			if resp, ok := stubAnswer(buf[:n], records); ok {
+				_, _ = conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// stubAnswer builds the response to a query
+func stubAnswer(query []byte, records map[string][]string) ([]byte, bool) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, false
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, false
+	}
+
+	addresses, found := records[strings.ToLower(q.Name.String())]
+
+	rh := dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true, RecursionDesired: h.RecursionDesired}
+	if !found {
+		rh.RCode = dnsmessage.RCodeNameError
+	}
+
+	b := dnsmessage.NewBuilder(nil, rh)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, false
+	}
+	if err := b.Question(q); err != nil {
+		return nil, false
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, false
+	}
+	if q.Type == dnsmessage.TypeA {
+		for _, a := range addresses {
+			var r dnsmessage.AResource
+			copy(r.A[:], net.ParseIP(a).To4())
+			err := b.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}, r)
+			if err != nil {
+				return nil, false
+			}
+		}
+	}
+
+	resp, err := b.Finish()
+	return resp, err == nil
+}
+
+func TestDNSChecker(t *testing.T) {
+	resolver := stubDNS(t, map[string][]string{
+		"www.example.test.": {"192.0.2.10", "192.0.2.11"},
+	}, 0)
+
+	tests := []struct {
+		name       string
+		host       models.Host
+		expected   string
+		status     string
+		errorClass string
+	}{
+		{"matching answer", models.Host{CanonicalName: "www.example.test"}, "192.0.2.11", "healthy", ""},
+		{"matching host address", models.Host{CanonicalName: "www.example.test", IP: "192.0.2.10"}, "", "healthy", ""},
+		{"mismatched answer", models.Host{CanonicalName: "www.example.test"}, "192.0.2.99", "warning", ErrorContent},
+		{"nxdomain", models.Host{CanonicalName: "missing.example.test"}, "", "problem", ErrorResolve},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := models.HostService{Config: models.CheckConfig{
+				DNSResolver:   resolver,
+				DNSRecordType: "A",
+				DNSExpected:   tt.expected,
+			}}
+
+			res := DNSChecker{}.Check(tt.host, hs)
+			if res.Status != tt.status || res.ErrorClass != tt.errorClass {
+				t.Errorf("got %s (%s) - %s, want %s (%s)", res.Status, res.ErrorClass, res.Message, tt.status, tt.errorClass)
+			}
+			if res.Latency <= 0 {
+				t.Errorf("latency is %s, want the time the lookup took", res.Latency)
+			}
+		})
+	}
+}
+
+func TestDNSCheckerLatencyThresholds(t *testing.T) {
+	resolver := stubDNS(t, map[string][]string{
+		"www.example.test.": {"192.0.2.10"},
+	}, 50*time.Millisecond)
+
+	hs := models.HostService{Config: models.CheckConfig{
+		DNSResolver:   resolver,
+		DNSRecordType: "A",
+		WarnLatency:   20,
+	}}
+	res := DNSChecker{}.Check(models.Host{CanonicalName: "www.example.test"}, hs)
+	if res.Status != "warning" || res.ErrorClass != ErrorLatency {
+		t.Errorf("got %s (%s) - %s, want warning (%s)", res.Status, res.ErrorClass, res.Message, ErrorLatency)
+	}
+}
@@ -0,0 +1,193 @@
+package checkers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/httpheaders"
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// maxBodyBytes is the most of a response body we read when matching against it
+const maxBodyBytes = 1 << 20
+
+// HTTPMethods are the request methods a host service may be configured with
+var HTTPMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+func init() {
+	Register("http", HTTPChecker{Scheme: "http"})
+	Register("https", HTTPChecker{Scheme: "https"})
+}
+
+// HTTPChecker requests the host's URL over the given scheme, using the host
+// service's request settings, and checks the status code and body
+type HTTPChecker struct {
+	Scheme string
+}
+
+// Check performs the http(s) check
+func (c HTTPChecker) Check(h models.Host, hs models.HostService) Result {
+	res := timed(func() Result { return c.request(h, hs) })
+	return applyLatencyThresholds(res, hs.Config)
+}
+
+// request makes the check's request and reads as much of the response as it needs
+func (c HTTPChecker) request(h models.Host, hs models.HostService) Result {
+	url := strings.TrimSuffix(h.URL, "/")
+
+	// force the scheme this checker is responsible for
+	if c.Scheme == "https" {
+		url = strings.Replace(url, "http://", "https://", -1)
+	} else {
+		url = strings.Replace(url, "https://", "http://", -1)
+	}
+
+	if hs.Config.HTTPPath != "" {
+		url = url + "/" + strings.TrimPrefix(hs.Config.HTTPPath, "/")
+	}
+
+	method := strings.ToUpper(hs.Config.HTTPMethod)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if hs.Config.HTTPBody != "" {
+		body = strings.NewReader(hs.Config.HTTPBody)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return Result{Status: "problem", ErrorClass: ErrorConfig, Message: fmt.Sprintf("%s - %s", url, "invalid request")}
+	}
+
+	headers, err := httpheaders.Parse(hs.Config.HTTPHeaders)
+	if err != nil {
+		return Result{Status: "problem", ErrorClass: ErrorConfig, Message: fmt.Sprintf("%s - %s", url, err)}
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+
+	client, settings := currentHTTPClient()
+	if req.Header.Get("User-Agent") == "" && settings.UserAgent != "" {
+		req.Header.Set("User-Agent", settings.UserAgent)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return Result{Status: "problem", ErrorClass: ErrorTimeout, Message: fmt.Sprintf("%s - timed out after %s", url, time.Since(start).Round(time.Millisecond))}
+		}
+		return Result{Status: "problem", ErrorClass: ErrorConnect, Message: fmt.Sprintf("%s - %s", url, "error connecting")}
+	}
+	defer resp.Body.Close()
+
+	ranges, err := ParseStatusRanges(hs.Config.ExpectedStatus)
+	if err != nil {
+		return Result{Status: "problem", ErrorClass: ErrorConfig, Message: fmt.Sprintf("%s - %s", url, err)}
+	}
+	if !ranges.Contains(resp.StatusCode) {
+		return Result{Status: "problem", ErrorClass: ErrorStatus, Message: fmt.Sprintf("%s - %s", url, resp.Status)}
+	}
+
+	if hs.Config.BodyMatch != "" {
+		content, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		if err != nil {
+			if isTimeout(err) {
+				return Result{Status: "problem", ErrorClass: ErrorTimeout, Message: fmt.Sprintf("%s - timed out reading body", url)}
+			}
+			return Result{Status: "problem", ErrorClass: ErrorConnect, Message: fmt.Sprintf("%s - %s", url, "error reading body")}
+		}
+
+		if msg, ok := matchBody(hs.Config, content); !ok {
+			return Result{Status: "problem", ErrorClass: ErrorContent, Message: fmt.Sprintf("%s - %s - %s", url, resp.Status, msg)}
+		}
+	}
+
+	return Result{
+		Status:  "healthy",
+		Message: fmt.Sprintf("%s - %s in %s", url, resp.Status, time.Since(start).Round(time.Millisecond)),
+	}
+}
+
+// matchBody checks a response body against the configured substring or regular
+// expression, and returns a message describing a failed match
+func matchBody(cfg models.CheckConfig, content []byte) (string, bool) {
+	var found bool
+	if cfg.BodyMatchRegex == 1 {
+		re, err := regexp.Compile(cfg.BodyMatch)
+		if err != nil {
+			return "invalid body match expression", false
+		}
+		found = re.Match(content)
+	} else {
+		found = strings.Contains(string(content), cfg.BodyMatch)
+	}
+
+	if cfg.BodyMatchNegate == 1 && found {
+		return fmt.Sprintf("body contains '%s'", cfg.BodyMatch), false
+	}
+	if cfg.BodyMatchNegate != 1 && !found {
+		return fmt.Sprintf("body does not contain '%s'", cfg.BodyMatch), false
+	}
+	return "", true
+}
+
+// StatusRange is an inclusive range of http status codes
+type StatusRange struct {
+	From int
+	To   int
+}
+
+// StatusRanges is a set of accepted http status codes
+type StatusRanges []StatusRange
+
+// Contains reports whether code falls in any of the ranges
+func (sr StatusRanges) Contains(code int) bool {
+	for _, r := range sr {
+		if code >= r.From && code <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatusRanges parses accepted status codes such as "200-299, 301". An
+// empty string accepts only 200, which is what checks have always expected.
+func ParseStatusRanges(s string) (StatusRanges, error) {
+	var ranges StatusRanges
+	for _, item := range splitList(s) {
+		from, to := item, item
+		if i := strings.Index(item, "-"); i > 0 {
+			from, to = item[:i], item[i+1:]
+		}
+
+		f, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code '%s'", item)
+		}
+		t, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code '%s'", item)
+		}
+		if f < 100 || t > 599 || f > t {
+			return nil, fmt.Errorf("invalid status range '%s'", item)
+		}
+		ranges = append(ranges, StatusRange{From: f, To: t})
+	}
+
+	if len(ranges) == 0 {
+		ranges = StatusRanges{{From: http.StatusOK, To: http.StatusOK}}
+	}
+	return ranges, nil
+}
@@ -0,0 +1,109 @@
+package checkers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+func TestHTTPChecker(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("all systems go"))
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("X-Token") != "secret" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		cfg        models.CheckConfig
+		status     string
+		errorClass string
+	}{
+		{"healthy", models.CheckConfig{}, "healthy", ""},
+		{"bad status", models.CheckConfig{HTTPPath: "/down"}, "problem", ErrorStatus},
+		{"accepted status", models.CheckConfig{HTTPPath: "/down", ExpectedStatus: "200, 500-599"}, "healthy", ""},
+		{"method and headers", models.CheckConfig{HTTPPath: "/created", HTTPMethod: "post", HTTPHeaders: "X-Token: secret", ExpectedStatus: "201"}, "healthy", ""},
+		{"body matches", models.CheckConfig{BodyMatch: "systems go"}, "healthy", ""},
+		{"body does not match", models.CheckConfig{BodyMatch: "outage"}, "problem", ErrorContent},
+		{"body must not match", models.CheckConfig{BodyMatch: "sys.*go", BodyMatchRegex: 1, BodyMatchNegate: 1}, "problem", ErrorContent},
+		{"slow", models.CheckConfig{HTTPPath: "/slow", WarnLatency: 20}, "warning", ErrorLatency},
+		{"invalid header", models.CheckConfig{HTTPHeaders: "no colon"}, "problem", ErrorConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := HTTPChecker{Scheme: "http"}.Check(models.Host{URL: srv.URL}, models.HostService{Config: tt.cfg})
+			if res.Status != tt.status || res.ErrorClass != tt.errorClass {
+				t.Errorf("got %s (%s) - %s, want %s (%s)", res.Status, res.ErrorClass, res.Message, tt.status, tt.errorClass)
+			}
+			if res.Latency <= 0 {
+				t.Errorf("latency is %s, want the time the check took", res.Latency)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerConnectionRefusedLatency(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	res := HTTPChecker{Scheme: "http"}.Check(models.Host{URL: url}, models.HostService{})
+	if res.Status != "problem" || res.ErrorClass != ErrorConnect {
+		t.Errorf("got %s (%s) - %s, want problem (%s)", res.Status, res.ErrorClass, res.Message, ErrorConnect)
+	}
+	if res.Latency <= 0 {
+		t.Errorf("latency is %s, want the time the failed attempt took", res.Latency)
+	}
+}
+
+func TestParseStatusRanges(t *testing.T) {
+	ranges, err := ParseStatusRanges("200-299, 301")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[int]bool{200: true, 299: true, 301: true, 300: false, 404: false} {
+		if ranges.Contains(code) != want {
+			t.Errorf("Contains(%d) = %v, want %v", code, !want, want)
+		}
+	}
+
+	for _, s := range []string{"abc", "299-200", "99", "200-600"} {
+		if _, err := ParseStatusRanges(s); err == nil {
+			t.Errorf("ParseStatusRanges(%q) did not fail", s)
+		}
+	}
+}
+
+func TestClientSettingsValidate(t *testing.T) {
+	s := DefaultClientSettings
+	s.Proxy = "http://proxy.example.test:3128"
+	if err := s.Validate(); err != nil {
+		t.Errorf("valid proxy: %s", err)
+	}
+
+	s.Proxy = "not a url"
+	if err := s.Validate(); err == nil {
+		t.Error("invalid proxy did not fail")
+	}
+	if got := currentSettings().Proxy; got == s.Proxy {
+		t.Error("Validate put the settings in use")
+	}
+}
@@ -0,0 +1,61 @@
+package checkers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/certificateutils"
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+func init() {
+	Register("ssl", SSLChecker{WarnDays: 30, ProblemDays: 7})
+}
+
+// SSLChecker checks how long the host's certificate has left before it expires
+type SSLChecker struct {
+	WarnDays    int
+	ProblemDays int
+}
+
+// Check performs the certificate expiry check
+func (c SSLChecker) Check(h models.Host, hs models.HostService) Result {
+	url := strings.TrimPrefix(h.URL, "https://")
+	url = strings.TrimPrefix(url, "http://")
+
+	var res Result
+
+	s := currentSettings()
+
+	start := time.Now()
+	certDetails, err := certificateutils.GetCertificateDetails(url, s.ConnectTimeout, s.TLSTimeout)
+	res.Latency = time.Since(start)
+	if err != nil {
+		res.Status = "problem"
+		if isTimeout(err) {
+			res.ErrorClass = ErrorTimeout
+			res.Message = fmt.Sprintf("%s - timed out after %s", url, time.Since(start).Round(time.Millisecond))
+		} else {
+			res.ErrorClass = ErrorConnect
+			res.Message = fmt.Sprintf("%s - %s", url, "error connecting")
+		}
+		return res
+	}
+
+	certificateutils.CheckExpirationStatus(&certDetails, c.WarnDays)
+	res.Message = certDetails.Hostname + " expiring in " + strconv.Itoa(certDetails.DaysUntilExpiration) + " days"
+
+	switch {
+	case certDetails.ExpiringSoon && certDetails.DaysUntilExpiration < c.ProblemDays:
+		res.Status = "problem"
+		res.ErrorClass = ErrorCertificate
+	case certDetails.ExpiringSoon:
+		res.Status = "warning"
+		res.ErrorClass = ErrorCertificate
+	default:
+		res.Status = "healthy"
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package checkers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// tlsServer serves a self-signed certificate that expires at notAfter on 127.0.0.1,
+// and returns its address
+func tlsServer(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-watch.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = conn.(*tls.Conn).Handshake()
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestSSLChecker(t *testing.T) {
+	day := 24 * time.Hour
+	checker := SSLChecker{WarnDays: 30, ProblemDays: 7}
+
+	tests := []struct {
+		name       string
+		expires    time.Duration
+		status     string
+		errorClass string
+	}{
+		{"valid", 90*day + time.Hour, "healthy", ""},
+		{"expiring soon", 10*day + time.Hour, "warning", ErrorCertificate},
+		{"about to expire", 3*day + time.Hour, "problem", ErrorCertificate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := tlsServer(t, time.Now().Add(tt.expires))
+
+			res := checker.Check(models.Host{URL: "https://" + address}, models.HostService{})
+			if res.Status != tt.status || res.ErrorClass != tt.errorClass {
+				t.Errorf("got %s (%s) - %s, want %s (%s)", res.Status, res.ErrorClass, res.Message, tt.status, tt.errorClass)
+			}
+		})
+	}
+}
+
+func TestSSLCheckerHandshakeTimeout(t *testing.T) {
+	s := DefaultClientSettings
+	s.TLSTimeout = 200 * time.Millisecond
+	if err := Configure(s); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Configure(DefaultClientSettings) })
+
+	// accepts connections, and never answers the client hello
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	stalled := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			stalled <- conn
+		}
+	}()
+	defer func() {
+		select {
+		case conn := <-stalled:
+			_ = conn.Close()
+		default:
+		}
+	}()
+
+	start := time.Now()
+	res := SSLChecker{WarnDays: 30, ProblemDays: 7}.Check(models.Host{URL: "https://" + ln.Addr().String()}, models.HostService{})
+	if res.Status != "problem" || res.ErrorClass != ErrorTimeout {
+		t.Errorf("got %s (%s) - %s, want problem (%s)", res.Status, res.ErrorClass, res.Message, ErrorTimeout)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("check took %s, longer than the tls timeout of %s allows", took, s.TLSTimeout)
+	}
+}
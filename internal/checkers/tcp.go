@@ -0,0 +1,66 @@
+package checkers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+func init() {
+	Register("tcp", TCPChecker{})
+}
+
+// TCPChecker checks that a port on the host accepts connections
+type TCPChecker struct{}
+
+// Check dials the configured port and reports the time it took to connect
+func (c TCPChecker) Check(h models.Host, hs models.HostService) Result {
+	if hs.Config.Port <= 0 || hs.Config.Port > 65535 {
+		return Result{Status: "problem", ErrorClass: ErrorConfig, Message: "no valid port configured for tcp check"}
+	}
+
+	address := net.JoinHostPort(hostAddress(h), strconv.Itoa(hs.Config.Port))
+
+	timeout := currentSettings().ConnectTimeout
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	latency := time.Since(start)
+	if err != nil {
+		if isTimeout(err) {
+			return Result{Status: "problem", ErrorClass: ErrorTimeout, Message: fmt.Sprintf("%s - timed out after %s", address, timeout), Latency: latency}
+		}
+		return Result{Status: "problem", ErrorClass: ErrorConnect, Message: fmt.Sprintf("%s - %s", address, "error connecting"), Latency: latency}
+	}
+	_ = conn.Close()
+
+	res := Result{
+		Status:  "healthy",
+		Message: fmt.Sprintf("%s - connected in %s", address, latency.Round(time.Millisecond)),
+		Latency: latency,
+	}
+	return applyLatencyThresholds(res, hs.Config)
+}
+
+// hostAddress returns the address to dial for a host: the IPv4 address if we
+// have one, otherwise the host part of the URL.
+func hostAddress(h models.Host) string {
+	if h.IP != "" {
+		return h.IP
+	}
+
+	raw := h.URL
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return h.URL
+	}
+	return u.Hostname()
+}
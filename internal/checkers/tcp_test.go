@@ -0,0 +1,57 @@
+package checkers
+
+import (
+	"net"
+	"testing"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+func TestTCPChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	// a port that was listening a moment ago, and is closed now
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	_ = closed.Close()
+
+	tests := []struct {
+		name       string
+		host       models.Host
+		port       int
+		status     string
+		errorClass string
+	}{
+		{"open port", models.Host{IP: "127.0.0.1"}, open, "healthy", ""},
+		{"address from url", models.Host{URL: "http://127.0.0.1/"}, open, "healthy", ""},
+		{"closed port", models.Host{IP: "127.0.0.1"}, closedPort, "problem", ErrorConnect},
+		{"no port", models.Host{IP: "127.0.0.1"}, 0, "problem", ErrorConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := models.HostService{Config: models.CheckConfig{Port: tt.port}}
+			res := TCPChecker{}.Check(tt.host, hs)
+			if res.Status != tt.status || res.ErrorClass != tt.errorClass {
+				t.Errorf("got %s (%s) - %s, want %s (%s)", res.Status, res.ErrorClass, res.Message, tt.status, tt.errorClass)
+			}
+		})
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"github.com/alexedwards/scs/v2"
 	"github.com/brianmaksy/go-watch/internal/channeldata"
 	"github.com/brianmaksy/go-watch/internal/driver"
+	"github.com/brianmaksy/go-watch/internal/executor"
+	"github.com/brianmaksy/go-watch/internal/leader"
+	"github.com/brianmaksy/go-watch/internal/monitor"
 	"github.com/pusher/pusher-http-go"
 	"github.com/robfig/cron/v3"
 )
@@ -16,8 +19,10 @@ type AppConfig struct {
 	Session       *scs.SessionManager
 	InProduction  bool
 	Domain        string
-	MonitorMap    map[int]cron.EntryID
-	PreferenceMap map[string]string
+	Monitors      *monitor.Registry
+	Checks        *executor.Executor
+	Leader        *leader.Elector
+	Preferences   *Preferences
 	Scheduler     *cron.Cron
 	WsClient      pusher.Client
 	PusherSecret  string
@@ -0,0 +1,55 @@
+package config
+
+import "sync"
+
+// Preferences holds the site preferences the application runs with. They are saved
+// from the settings page while checks, notifications and the leader election read
+// them, so they are only ever read or written through its methods.
+type Preferences struct {
+	mu sync.RWMutex
+	m  map[string]string
+}
+
+// NewPreferences returns preferences holding a copy of m
+func NewPreferences(m map[string]string) *Preferences {
+	p := &Preferences{m: make(map[string]string, len(m))}
+	for k, v := range m {
+		p.m[k] = v
+	}
+	return p
+}
+
+// Get returns a preference, or "" if it is not set
+func (p *Preferences) Get(name string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.m[name]
+}
+
+// Set sets a preference
+func (p *Preferences) Set(name, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.m[name] = value
+}
+
+// SetAll sets every preference in m at once
+func (p *Preferences) SetAll(m map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, v := range m {
+		p.m[k] = v
+	}
+}
+
+// Map returns a copy of the preferences, for code that reads several of them, such as
+// templates and notifiers
+func (p *Preferences) Map() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	pm := make(map[string]string, len(p.m))
+	for k, v := range p.m {
+		pm[k] = v
+	}
+	return pm
+}
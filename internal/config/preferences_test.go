@@ -0,0 +1,52 @@
+package config
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestPreferences(t *testing.T) {
+	m := map[string]string{"monitoring_live": "0"}
+	p := NewPreferences(m)
+	m["monitoring_live"] = "1"
+	if v := p.Get("monitoring_live"); v != "0" {
+		t.Errorf("changing the map it was made from changed a preference to %q", v)
+	}
+
+	copied := p.Map()
+	copied["monitoring_live"] = "1"
+	if v := p.Get("monitoring_live"); v != "0" {
+		t.Errorf("changing a copy changed a preference to %q", v)
+	}
+
+	p.SetAll(map[string]string{"flap_threshold": "3", "monitoring_live": "1"})
+	if p.Get("flap_threshold") != "3" || p.Get("monitoring_live") != "1" {
+		t.Errorf("got %v after SetAll", p.Map())
+	}
+}
+
+func TestPreferencesConcurrentUse(t *testing.T) {
+	p := NewPreferences(nil)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				switch i % 4 {
+				case 0:
+					p.Set("monitoring_live", strconv.Itoa(i%2))
+				case 1:
+					p.SetAll(map[string]string{"flap_threshold": strconv.Itoa(w)})
+				case 2:
+					_ = p.Get("monitoring_live")
+				case 3:
+					_ = p.Map()["flap_threshold"]
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
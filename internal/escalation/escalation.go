@@ -0,0 +1,126 @@
+package escalation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// Due is a step of an escalation policy that should be notified now
+type Due struct {
+	Step     models.EscalationStep
+	Position int  // 1 for the first step
+	Reminder bool // true when repeating a step that was already notified
+}
+
+// Evaluate returns the steps of a policy due for an incident at now. Every step whose
+// delay has passed since the incident opened and that has not been notified yet is due.
+// If none is, the latest notified step is due again as a reminder once RepeatMinutes
+// have passed since the last escalation. Nothing is due once the incident has been
+// acknowledged or resolved.
+func Evaluate(p models.EscalationPolicy, i models.Incident, now time.Time) []Due {
+	if p.Active != 1 || len(p.Steps) == 0 || i.Status != "open" {
+		return nil
+	}
+
+	elapsed := now.Sub(i.OpenedAt)
+
+	var due []Due
+	for n := i.EscalationStep; n < len(p.Steps); n++ {
+		if elapsed < time.Duration(p.Steps[n].DelayMinutes)*time.Minute {
+			break
+		}
+		due = append(due, Due{Step: p.Steps[n], Position: n + 1})
+	}
+	if len(due) > 0 {
+		return due
+	}
+
+	reached := i.EscalationStep
+	if reached > len(p.Steps) {
+		reached = len(p.Steps)
+	}
+	if reached > 0 && p.RepeatMinutes > 0 &&
+		!now.Before(i.LastEscalatedAt.Add(time.Duration(p.RepeatMinutes)*time.Minute)) {
+		return []Due{{Step: p.Steps[reached-1], Position: reached, Reminder: true}}
+	}
+	return nil
+}
+
+// Validate returns an error describing the first problem with a policy. channels are
+// the notifier names a step may use.
+func Validate(p models.EscalationPolicy, channels []string) error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("please enter a name")
+	}
+	if p.RepeatMinutes < 0 {
+		return errors.New("the reminder interval cannot be negative")
+	}
+	if len(p.Steps) == 0 {
+		return errors.New("please add at least one step")
+	}
+
+	previous := 0
+	for n, s := range p.Steps {
+		if s.DelayMinutes < previous {
+			return fmt.Errorf("step %d must not come before step %d", n+1, n)
+		}
+		previous = s.DelayMinutes
+
+		if len(UserIDs(s)) == 0 && len(Channels(s)) == 0 {
+			return fmt.Errorf("step %d needs at least one user or channel", n+1)
+		}
+		for _, c := range Channels(s) {
+			if !inList(c, channels) {
+				return fmt.Errorf("step %d uses unknown channel '%s'", n+1, c)
+			}
+		}
+	}
+	return nil
+}
+
+// UserIDs returns the ids of the users a step notifies
+func UserIDs(s models.EscalationStep) []int {
+	var ids []int
+	for _, f := range strings.Split(s.UserIDs, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(f))
+		if err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Channels returns the names of the notifiers a step notifies through
+func Channels(s models.EscalationStep) []string {
+	var names []string
+	for _, f := range strings.Split(s.Channels, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			names = append(names, f)
+		}
+	}
+	return names
+}
+
+// JoinIDs formats ids the way UserIDs reads them
+func JoinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for n, id := range ids {
+		s[n] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
+}
+
+// inList reports whether s is in list
+func inList(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package escalation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+var opened = time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+// policy notifies at once, after 15 minutes and after an hour, and reminds every 30
+var policy = models.EscalationPolicy{
+	Active:        1,
+	RepeatMinutes: 30,
+	Steps: []models.EscalationStep{
+		{DelayMinutes: 0, UserIDs: "1"},
+		{DelayMinutes: 15, UserIDs: "2"},
+		{DelayMinutes: 60, Channels: "slack"},
+	},
+}
+
+// due describes a step that should be notified, for comparing results
+type due struct {
+	position int
+	reminder bool
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		reached   int           // steps already notified
+		lastAfter time.Duration // when the last escalation was, after the incident opened
+		at        time.Duration // when it is evaluated, after the incident opened
+		want      []due
+	}{
+		{"first step as the incident opens", "open", 0, 0, 0, []due{{1, false}}},
+		{"second step not yet due", "open", 1, 0, 14*time.Minute + 59*time.Second, nil},
+		{"second step on its delay", "open", 1, 0, 15 * time.Minute, []due{{2, false}}},
+		{"steps missed are all due", "open", 0, 0, 61 * time.Minute, []due{{1, false}, {2, false}, {3, false}}},
+		{"steps already notified are not due", "open", 2, 15 * time.Minute, 60 * time.Minute, []due{{3, false}}},
+		{"no reminder before the repeat interval", "open", 2, 15 * time.Minute, 44*time.Minute + 59*time.Second, nil},
+		{"reminder of the latest step after the repeat interval", "open", 2, 15 * time.Minute, 45 * time.Minute, []due{{2, true}}},
+		{"reminder of the last step", "open", 3, 60 * time.Minute, 90 * time.Minute, []due{{3, true}}},
+		{"reminder again after the repeat interval", "open", 3, 90 * time.Minute, 120 * time.Minute, []due{{3, true}}},
+		{"a step reached past the end reminds of the last", "open", 5, 60 * time.Minute, 90 * time.Minute, []due{{3, true}}},
+		{"stops once acknowledged", "acknowledged", 1, 0, 20 * time.Minute, nil},
+		{"no reminder once acknowledged", "acknowledged", 3, 60 * time.Minute, 120 * time.Minute, nil},
+		{"stops once resolved", "resolved", 1, 0, 20 * time.Minute, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := models.Incident{
+				Status:          tt.status,
+				OpenedAt:        opened,
+				EscalationStep:  tt.reached,
+				LastEscalatedAt: opened.Add(tt.lastAfter),
+			}
+
+			got := Evaluate(policy, i, opened.Add(tt.at))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d steps due, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for n, d := range got {
+				if d.Position != tt.want[n].position || d.Reminder != tt.want[n].reminder {
+					t.Errorf("got step %d (reminder %v), want step %d (reminder %v)", d.Position, d.Reminder, tt.want[n].position, tt.want[n].reminder)
+				}
+				if d.Step != policy.Steps[d.Position-1] {
+					t.Errorf("step %d is %+v, want %+v", d.Position, d.Step, policy.Steps[d.Position-1])
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateInactiveOrEmptyPolicy(t *testing.T) {
+	i := models.Incident{Status: "open", OpenedAt: opened}
+
+	inactive := policy
+	inactive.Active = 0
+	if got := Evaluate(inactive, i, opened.Add(time.Hour)); got != nil {
+		t.Errorf("inactive policy has steps due: %+v", got)
+	}
+
+	empty := models.EscalationPolicy{Active: 1, RepeatMinutes: 30}
+	if got := Evaluate(empty, i, opened.Add(time.Hour)); got != nil {
+		t.Errorf("policy without steps has steps due: %+v", got)
+	}
+}
+
+func TestEvaluateWithoutReminders(t *testing.T) {
+	once := policy
+	once.RepeatMinutes = 0
+
+	i := models.Incident{Status: "open", OpenedAt: opened, EscalationStep: 3, LastEscalatedAt: opened.Add(time.Hour)}
+	if got := Evaluate(once, i, opened.Add(24*time.Hour)); got != nil {
+		t.Errorf("got %+v due, want no reminders when RepeatMinutes is 0", got)
+	}
+}
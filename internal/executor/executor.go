@@ -0,0 +1,238 @@
+package executor
+
+import (
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Settings controls how many checks run at once, and how their start times are spread
+type Settings struct {
+	Workers int           // checks running at once, across all hosts
+	PerHost int           // checks running at once against one host
+	Jitter  time.Duration // most a check is held back by, to spread checks that fall due together
+}
+
+// DefaultSettings are used for any preference that is missing or invalid
+var DefaultSettings = Settings{
+	Workers: 10,
+	PerHost: 2,
+	Jitter:  10 * time.Second,
+}
+
+// jitterShare is the largest part of its period a check is held back by, so frequent
+// checks are not delayed by as much as their whole interval
+const jitterShare = 10
+
+// SettingsFromPreferences builds executor settings from the preference map. Jitter is
+// stored in seconds.
+func SettingsFromPreferences(pm map[string]string) Settings {
+	s := DefaultSettings
+
+	if n, err := strconv.Atoi(pm["check_workers"]); err == nil && n > 0 {
+		s.Workers = n
+	}
+	if n, err := strconv.Atoi(pm["check_workers_per_host"]); err == nil && n > 0 {
+		s.PerHost = n
+	}
+	if n, err := strconv.Atoi(pm["check_jitter_seconds"]); err == nil && n >= 0 {
+		s.Jitter = time.Duration(n) * time.Second
+	}
+	return s
+}
+
+// Task is a check of a host service that has fallen due
+type Task struct {
+	HostServiceID int
+	HostID        int
+	Period        time.Duration // how often the check falls due, which caps its jitter
+}
+
+// Stats describes the queue of an executor at one moment
+type Stats struct {
+	Workers   int
+	PerHost   int
+	Jitter    time.Duration
+	Delayed   int // held back by jitter
+	Queued    int // waiting for a worker, or for a host to have a free slot
+	Running   int
+	PeakQueue int    // most checks queued at once since start
+	Completed uint64 // checks run since start
+	Skipped   uint64 // checks not queued as the host service was already queued or running
+}
+
+// Executor runs checks on a bounded pool of workers, never running more than PerHost
+// checks against one host at once. A check that falls due while the last one of the
+// same host service is still waiting or running is skipped.
+type Executor struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	run      func(hostServiceID int)
+	settings Settings
+	workers  int          // workers started and not yet stopped
+	pending  []Task       // waiting for a worker, oldest first
+	busy     map[int]int  // checks running, by host id
+	due      map[int]bool // host service ids delayed, queued or running
+	delayed  int
+	running  int
+	peak     int
+	done     uint64
+	skipped  uint64
+}
+
+// New returns an executor that runs the check of a host service with run, and starts
+// its workers
+func New(s Settings, run func(hostServiceID int)) *Executor {
+	e := &Executor{
+		run:  run,
+		busy: make(map[int]int),
+		due:  make(map[int]bool),
+	}
+	e.cond = sync.NewCond(&e.mu)
+	e.Configure(s)
+	return e
+}
+
+// Configure changes the settings of the executor, starting workers if the pool has
+// grown. If it has shrunk, surplus workers stop once they are done with their check.
+func (e *Executor) Configure(s Settings) {
+	if s.Workers < 1 {
+		s.Workers = DefaultSettings.Workers
+	}
+	if s.PerHost < 1 {
+		s.PerHost = DefaultSettings.PerHost
+	}
+	if s.Jitter < 0 {
+		s.Jitter = 0
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.settings = s
+	for e.workers < s.Workers {
+		e.workers++
+		go e.work()
+	}
+	e.cond.Broadcast()
+}
+
+// Submit queues a check, after a random delay of up to the jitter. It reports false if
+// the check was skipped because the host service is already queued or running.
+func (e *Executor) Submit(t Task) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.due[t.HostServiceID] {
+		e.skipped++
+		return false
+	}
+	e.due[t.HostServiceID] = true
+
+	max := e.settings.Jitter
+	if t.Period > 0 && t.Period/jitterShare < max {
+		max = t.Period / jitterShare
+	}
+	if max <= 0 {
+		e.enqueue(t)
+		return true
+	}
+
+	e.delayed++
+	time.AfterFunc(time.Duration(rand.Int63n(int64(max))), func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.delayed--
+		e.enqueue(t)
+	})
+	return true
+}
+
+// enqueue puts a check at the back of the queue. The caller holds the lock.
+func (e *Executor) enqueue(t Task) {
+	e.pending = append(e.pending, t)
+	if len(e.pending) > e.peak {
+		e.peak = len(e.pending)
+	}
+	e.cond.Signal()
+}
+
+// Stats returns the current state of the queue
+func (e *Executor) Stats() Stats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return Stats{
+		Workers:   e.settings.Workers,
+		PerHost:   e.settings.PerHost,
+		Jitter:    e.settings.Jitter,
+		Delayed:   e.delayed,
+		Queued:    len(e.pending),
+		Running:   e.running,
+		PeakQueue: e.peak,
+		Completed: e.done,
+		Skipped:   e.skipped,
+	}
+}
+
+// work runs queued checks until the pool shrinks below this worker
+func (e *Executor) work() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for {
+		if e.workers > e.settings.Workers {
+			e.workers--
+			// another worker may be able to take what this one leaves
+			e.cond.Signal()
+			return
+		}
+
+		t, ok := e.next()
+		if !ok {
+			e.cond.Wait()
+			continue
+		}
+
+		e.busy[t.HostID]++
+		e.running++
+		e.mu.Unlock()
+
+		e.runCheck(t)
+
+		e.mu.Lock()
+		e.busy[t.HostID]--
+		if e.busy[t.HostID] == 0 {
+			delete(e.busy, t.HostID)
+		}
+		e.running--
+		e.done++
+		delete(e.due, t.HostServiceID)
+		// a slot for this host is free again
+		e.cond.Broadcast()
+	}
+}
+
+// runCheck runs a check, recovering from a panic so the worker and its counts survive it
+func (e *Executor) runCheck(t Task) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("check of host service", t.HostServiceID, "panicked:", r)
+		}
+	}()
+	e.run(t.HostServiceID)
+}
+
+// next takes the oldest queued check whose host has a free slot off the queue. The
+// caller holds the lock.
+func (e *Executor) next() (Task, bool) {
+	for i, t := range e.pending {
+		if e.busy[t.HostID] < e.settings.PerHost {
+			e.pending = append(e.pending[:i], e.pending[i+1:]...)
+			return t, true
+		}
+	}
+	return Task{}, false
+}
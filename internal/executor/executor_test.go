@@ -0,0 +1,207 @@
+package executor
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// blocker is a check that holds its worker until released, and counts how many checks
+// run at once, in all and by host
+type blocker struct {
+	mu      sync.Mutex
+	hostOf  map[int]int // host id of each host service id
+	running int
+	peak    int
+	byHost  map[int]int
+	hostMax map[int]int
+	runs    map[int]int // times each host service was checked
+	release chan struct{}
+}
+
+func newBlocker() *blocker {
+	return &blocker{
+		hostOf:  make(map[int]int),
+		byHost:  make(map[int]int),
+		hostMax: make(map[int]int),
+		runs:    make(map[int]int),
+		release: make(chan struct{}),
+	}
+}
+
+// submit hands a check of a host service on a host to e
+func (b *blocker) submit(e *Executor, hostServiceID, hostID int) bool {
+	b.mu.Lock()
+	b.hostOf[hostServiceID] = hostID
+	b.mu.Unlock()
+	return e.Submit(Task{HostServiceID: hostServiceID, HostID: hostID})
+}
+
+func (b *blocker) run(hostServiceID int) {
+	b.mu.Lock()
+	host := b.hostOf[hostServiceID]
+	b.running++
+	if b.running > b.peak {
+		b.peak = b.running
+	}
+	b.byHost[host]++
+	if b.byHost[host] > b.hostMax[host] {
+		b.hostMax[host] = b.byHost[host]
+	}
+	b.runs[hostServiceID]++
+	b.mu.Unlock()
+
+	<-b.release
+
+	b.mu.Lock()
+	b.running--
+	b.byHost[host]--
+	b.mu.Unlock()
+}
+
+// waitFor fails the test if cond does not hold within a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExecutorWorkerLimit(t *testing.T) {
+	b := newBlocker()
+	e := New(Settings{Workers: 3, PerHost: 10}, b.run)
+
+	for id := 1; id <= 10; id++ {
+		b.submit(e, id, id) // every check on its own host
+	}
+
+	waitFor(t, "three checks to run", func() bool { return e.Stats().Running == 3 })
+	time.Sleep(20 * time.Millisecond) // time for a fourth worker to start one, if there were one
+	if s := e.Stats(); s.Running != 3 || s.Queued != 7 {
+		t.Errorf("got %d running and %d queued, want 3 and 7", s.Running, s.Queued)
+	}
+
+	close(b.release)
+	waitFor(t, "every check to complete", func() bool { return e.Stats().Completed == 10 })
+	if b.peak != 3 {
+		t.Errorf("ran %d checks at once, want 3", b.peak)
+	}
+}
+
+func TestExecutorPerHostLimit(t *testing.T) {
+	b := newBlocker()
+	e := New(Settings{Workers: 10, PerHost: 2}, b.run)
+
+	for id := 1; id <= 6; id++ {
+		b.submit(e, id, 1)
+	}
+	b.submit(e, 7, 2)
+	b.submit(e, 8, 2)
+
+	// two for each host, although there are workers to spare
+	waitFor(t, "four checks to run", func() bool { return e.Stats().Running == 4 })
+	time.Sleep(20 * time.Millisecond)
+	if s := e.Stats(); s.Running != 4 || s.Queued != 4 {
+		t.Errorf("got %d running and %d queued, want 4 and 4", s.Running, s.Queued)
+	}
+
+	close(b.release)
+	waitFor(t, "every check to complete", func() bool { return e.Stats().Completed == 8 })
+	for host, max := range b.hostMax {
+		if max > 2 {
+			t.Errorf("ran %d checks against host %d at once, want at most 2", max, host)
+		}
+	}
+}
+
+func TestExecutorJitter(t *testing.T) {
+	ran := make(chan int, 1)
+	run := func(id int) { ran <- id }
+
+	t.Run("capped at a tenth of the period", func(t *testing.T) {
+		e := New(Settings{Workers: 1, PerHost: 1, Jitter: 10 * time.Second}, run)
+
+		// at most 5ms late, rather than up to 10s
+		e.Submit(Task{HostServiceID: 1, HostID: 1, Period: 50 * time.Millisecond})
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatal("check held back by more than a tenth of its period")
+		}
+	})
+
+	t.Run("the whole jitter without a period", func(t *testing.T) {
+		e := New(Settings{Workers: 1, PerHost: 1, Jitter: time.Hour}, run)
+
+		e.Submit(Task{HostServiceID: 1, HostID: 1})
+		if s := e.Stats(); s.Delayed != 1 || s.Queued != 0 {
+			t.Errorf("got %d delayed and %d queued, want the check delayed", s.Delayed, s.Queued)
+		}
+	})
+
+	t.Run("no jitter", func(t *testing.T) {
+		e := New(Settings{Workers: 1, PerHost: 1, Jitter: 0}, run)
+
+		e.Submit(Task{HostServiceID: 1, HostID: 1, Period: time.Minute})
+		if s := e.Stats(); s.Delayed != 0 {
+			t.Errorf("got %d delayed, want none", s.Delayed)
+		}
+		<-ran
+	})
+}
+
+func TestExecutorSkipsCheckAlreadyDue(t *testing.T) {
+	b := newBlocker()
+	e := New(Settings{Workers: 1, PerHost: 1}, b.run)
+
+	if !b.submit(e, 1, 1) {
+		t.Fatal("first check of host service 1 was skipped")
+	}
+	waitFor(t, "host service 1 to run", func() bool { return e.Stats().Running == 1 })
+	if !b.submit(e, 2, 1) {
+		t.Fatal("first check of host service 2 was skipped")
+	}
+
+	if b.submit(e, 1, 1) {
+		t.Error("queued a check of host service 1 while it is running")
+	}
+	if b.submit(e, 2, 1) {
+		t.Error("queued a check of host service 2 while it is queued")
+	}
+	if s := e.Stats(); s.Queued != 1 || s.Skipped != 2 {
+		t.Errorf("got %d queued and %d skipped, want 1 and 2", s.Queued, s.Skipped)
+	}
+
+	close(b.release)
+	waitFor(t, "both checks to complete", func() bool { return e.Stats().Completed == 2 })
+	if b.runs[1] != 1 || b.runs[2] != 1 {
+		t.Errorf("checked host services %v times, want once each", b.runs)
+	}
+
+	// once done, a host service can be queued again
+	if !b.submit(e, 2, 1) {
+		t.Error("skipped a check of a host service that is no longer due")
+	}
+	waitFor(t, "the new check to complete", func() bool { return e.Stats().Completed == 3 })
+}
+
+func TestExecutorSurvivesPanic(t *testing.T) {
+	e := New(Settings{Workers: 1, PerHost: 1}, func(id int) {
+		if id == 1 {
+			panic("check failed badly")
+		}
+	})
+
+	e.Submit(Task{HostServiceID: 1, HostID: 1})
+	e.Submit(Task{HostServiceID: 2, HostID: 1})
+	waitFor(t, "both checks to complete", func() bool { return e.Stats().Completed == 2 })
+
+	if !e.Submit(Task{HostServiceID: 1, HostID: 1}) {
+		t.Error("host service whose check panicked is still marked as due")
+	}
+}
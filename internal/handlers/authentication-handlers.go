@@ -77,7 +77,7 @@ func (repo *DBRepo) Login(w http.ResponseWriter, r *http.Request) {
 		// write a cookie
 		expire := time.Now().Add(365 * 24 * 60 * 60 * time.Second)
 		cookie := http.Cookie{
-			Name:     fmt.Sprintf("_%s_gowatcher_remember", app.PreferenceMap["identifier"]),
+			Name:     fmt.Sprintf("_%s_gowatcher_remember", app.Preferences.Get("identifier")),
 			Value:    fmt.Sprintf("%d|%s", id, sha),
 			Path:     "/",
 			Expires:  expire,
@@ -115,7 +115,7 @@ func (repo *DBRepo) Login(w http.ResponseWriter, r *http.Request) {
 func (repo *DBRepo) Logout(w http.ResponseWriter, r *http.Request) {
 
 	// delete the remember me token, if any
-	cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", app.PreferenceMap["identifier"]))
+	cookie, err := r.Cookie(fmt.Sprintf("_%s_gowatcher_remember", app.Preferences.Get("identifier")))
 	if err != nil {
 	} else {
 		key := cookie.Value
@@ -133,7 +133,7 @@ func (repo *DBRepo) Logout(w http.ResponseWriter, r *http.Request) {
 
 	// delete the remember me cookie, if any
 	delCookie := http.Cookie{
-		Name:     fmt.Sprintf("_%s_gowatcher_remember", app.PreferenceMap["identifier"]),
+		Name:     fmt.Sprintf("_%s_gowatcher_remember", app.Preferences.Get("identifier")),
 		Value:    "",
 		Domain:   app.Domain,
 		Path:     "/",
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/robfig/cron/v3"
+)
+
+// pruneSchedule is when check results older than the retention period are deleted
+const pruneSchedule = "@daily"
+
+// defaultRetentionDays is how long check results are kept when check_results_retention_days
+// is missing or invalid
+const defaultRetentionDays = 90
+
+// pruneJob is the scheduler job that deletes old check results
+type pruneJob struct{}
+
+// Run prunes check results
+func (p pruneJob) Run() {
+	Repo.PruneCheckResults()
+}
+
+// pruneEntry is the scheduler entry of the prune job, once added
+var (
+	pruneMu    sync.Mutex
+	pruneEntry cron.EntryID
+)
+
+// schedulePruning adds the prune job to the scheduler, unless it is there already
+func (repo *DBRepo) schedulePruning() {
+	pruneMu.Lock()
+	defer pruneMu.Unlock()
+
+	if repo.App.Scheduler.Entry(pruneEntry).Valid() {
+		return
+	}
+	id, err := repo.App.Scheduler.AddJob(pruneSchedule, pruneJob{})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	pruneEntry = id
+}
+
+// PruneCheckResults deletes the check results older than the retention period. A
+// retention of 0 days keeps them for ever.
+func (repo *DBRepo) PruneCheckResults() {
+	days := retentionDays(repo.App.Preferences.Get("check_results_retention_days"))
+	if days == 0 {
+		return
+	}
+
+	n, err := repo.DB.DeleteCheckResultsBefore(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Println("Pruned", n, "check results older than", days, "days")
+}
+
+// retentionDays reads the retention period from its preference
+func retentionDays(pref string) int {
+	days, err := strconv.Atoi(pref)
+	if err != nil || days < 0 {
+		return defaultRetentionDays
+	}
+	return days
+}
+
+type checkResultJSON struct {
+	ID         int       `json:"id"`
+	Status     string    `json:"status"`
+	LatencyMS  int       `json:"latency_ms"`
+	Message    string    `json:"message"`
+	ErrorClass string    `json:"error_class"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type checkResultsResp struct {
+	OK            bool              `json:"ok"`
+	Message       string            `json:"message"`
+	HostServiceID int               `json:"host_service_id"`
+	From          time.Time         `json:"from"`
+	To            time.Time         `json:"to"`
+	Results       []checkResultJSON `json:"results"`
+}
+
+// CheckResults sends the check history of a host service as JSON. The window is
+// given by the from and to query parameters (RFC 3339), and defaults to the last 24 hours.
+func (repo *DBRepo) CheckResults(w http.ResponseWriter, r *http.Request) {
+	hostServiceID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	resp := checkResultsResp{
+		OK:            true,
+		HostServiceID: hostServiceID,
+		Results:       []checkResultJSON{},
+	}
+
+	from, to, err := timeWindowFromRequest(r, 24*time.Hour)
+	if err != nil {
+		resp.OK = false
+		resp.Message = err.Error()
+	}
+	resp.From, resp.To = from, to
+
+	if resp.OK {
+		var results []models.CheckResult
+		results, err = repo.DB.GetCheckResultsForHostService(hostServiceID, from, to)
+		if err != nil {
+			log.Println(err)
+			resp.OK = false
+			resp.Message = "Could not get check results"
+		}
+
+		for _, x := range results {
+			resp.Results = append(resp.Results, checkResultJSON{
+				ID:         x.ID,
+				Status:     x.Status,
+				LatencyMS:  x.LatencyMS,
+				Message:    x.Message,
+				ErrorClass: x.ErrorClass,
+				CheckedAt:  x.CheckedAt,
+			})
+		}
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// timeWindowFromRequest reads the from and to query parameters (RFC 3339). A missing
+// to is now, and a missing from is def before to.
+func timeWindowFromRequest(r *http.Request, def time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+
+	from := to.Add(-def)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+
+	return from, to, nil
+}
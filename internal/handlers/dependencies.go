@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// hostTreeRow is one line of the hosts list in dependency tree view. A host with more
+// than one parent appears under each of them.
+type hostTreeRow struct {
+	Host    models.Host
+	Depth   int
+	Parents []string
+}
+
+// downParent returns a description of the first parent a host service depends on that
+// is down, or an empty string if there is none
+func (repo *DBRepo) downParent(hs models.HostService) string {
+	parent, err := repo.DB.GetDownParent(hs.HostID, hs.ID)
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
+	return parent
+}
+
+// AddHostDependency makes a host, or one of its services, depend on a parent host or
+// parent host service, and returns JSON
+func (repo *DBRepo) AddHostDependency(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+	resp.OK = true
+
+	var d models.HostDependency
+	d.HostID, _ = strconv.Atoi(r.Form.Get("host_id"))
+	d.HostServiceID, _ = strconv.Atoi(r.Form.Get("host_service_id"))
+
+	// the parent is posted as host id:host service id, with 0 meaning any service
+	parent := strings.Split(r.Form.Get("parent"), ":")
+	d.ParentHostID, _ = strconv.Atoi(parent[0])
+	if len(parent) > 1 {
+		d.ParentHostServiceID, _ = strconv.Atoi(parent[1])
+	}
+
+	deps, err := repo.DB.AllHostDependencies()
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = "Could not load dependencies"
+	}
+
+	// services must belong to the hosts they are chosen for
+	if resp.OK && !repo.hostServiceOnHost(d.HostServiceID, d.HostID) {
+		resp.OK = false
+		resp.Message = "Service not found on this host"
+	}
+	if resp.OK && !repo.hostServiceOnHost(d.ParentHostServiceID, d.ParentHostID) {
+		resp.OK = false
+		resp.Message = "Service not found on the parent host"
+	}
+
+	if resp.OK {
+		if msg := validateHostDependency(deps, d); msg != "" {
+			resp.OK = false
+			resp.Message = msg
+		}
+	}
+
+	if resp.OK {
+		_, err = repo.DB.InsertHostDependency(d)
+		if err != nil {
+			log.Println(err)
+			resp.OK = false
+			resp.Message = "Could not save dependency"
+		}
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// RemoveHostDependency deletes a host dependency and returns JSON
+func (repo *DBRepo) RemoveHostDependency(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+	resp.OK = true
+
+	id, _ := strconv.Atoi(r.Form.Get("id"))
+	err := repo.DB.DeleteHostDependency(id)
+	if err != nil {
+		resp.OK = false
+		resp.Message = "Could not remove dependency"
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// hostServiceOnHost reports whether a host service belongs to a host; 0, meaning the
+// whole host, always does
+func (repo *DBRepo) hostServiceOnHost(hostServiceID, hostID int) bool {
+	if hostServiceID == 0 {
+		return true
+	}
+	hs, err := repo.DB.GetHostServiceByID(hostServiceID)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return hs.HostID == hostID
+}
+
+// validateHostDependency returns a message describing why d cannot be added to deps, or
+// an empty string if it can
+func validateHostDependency(deps []models.HostDependency, d models.HostDependency) string {
+	if d.HostID == 0 || d.ParentHostID == 0 {
+		return "Please choose a parent"
+	}
+	if d.HostID == d.ParentHostID {
+		return "A host cannot depend on itself"
+	}
+
+	for _, existing := range deps {
+		if existing.HostID == d.HostID && existing.HostServiceID == d.HostServiceID &&
+			existing.ParentHostID == d.ParentHostID && existing.ParentHostServiceID == d.ParentHostServiceID {
+			return "That dependency already exists"
+		}
+	}
+
+	// the new parent must not already depend on the host, directly or further up
+	parents := hostParents(deps)
+	seen := make(map[int]bool)
+	queue := []int{d.ParentHostID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == d.HostID {
+			return "The parent already depends on this host, which would make a loop"
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		queue = append(queue, parents[id]...)
+	}
+	return ""
+}
+
+// hostParents returns the ids of the hosts each host depends on, by host id
+func hostParents(deps []models.HostDependency) map[int][]int {
+	parents := make(map[int][]int)
+	for _, d := range deps {
+		if !inIntList(d.ParentHostID, parents[d.HostID]) {
+			parents[d.HostID] = append(parents[d.HostID], d.ParentHostID)
+		}
+	}
+	return parents
+}
+
+// dependencyTree orders hosts so that each host follows the hosts it depends on,
+// indented one level deeper. Hosts with no parents are the roots.
+func dependencyTree(hosts []models.Host, deps []models.HostDependency) []hostTreeRow {
+	parents := hostParents(deps)
+
+	children := make(map[int][]models.Host)
+	for _, h := range hosts {
+		for _, p := range parents[h.ID] {
+			children[p] = append(children[p], h)
+		}
+	}
+
+	names := make(map[int]string)
+	for _, h := range hosts {
+		names[h.ID] = h.HostName
+	}
+
+	var rows []hostTreeRow
+	placed := make(map[int]bool)
+
+	var walk func(h models.Host, depth int, path map[int]bool)
+	walk = func(h models.Host, depth int, path map[int]bool) {
+		var parentNames []string
+		for _, p := range parents[h.ID] {
+			parentNames = append(parentNames, names[p])
+		}
+		rows = append(rows, hostTreeRow{Host: h, Depth: depth, Parents: parentNames})
+		placed[h.ID] = true
+
+		path[h.ID] = true
+		for _, c := range children[h.ID] {
+			if !path[c.ID] {
+				walk(c, depth+1, path)
+			}
+		}
+		delete(path, h.ID)
+	}
+
+	for _, h := range hosts {
+		if len(parents[h.ID]) == 0 {
+			walk(h, 0, make(map[int]bool))
+		}
+	}
+
+	// hosts only reachable through a cycle still need to be listed
+	for _, h := range hosts {
+		if !placed[h.ID] {
+			walk(h, 0, make(map[int]bool))
+		}
+	}
+	return rows
+}
+
+// inIntList reports whether n is in list
+func inIntList(n int, list []int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
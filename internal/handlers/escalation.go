@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/escalation"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/notifiers"
+	"github.com/go-chi/chi/v5"
+	"github.com/robfig/cron/v3"
+)
+
+// escalationSchedule is how often unacknowledged incidents are checked for escalation
+const escalationSchedule = "@every 1m"
+
+// escalationJob is the scheduler job that escalates unacknowledged incidents
+type escalationJob struct{}
+
+// Run evaluates escalation policies
+func (e escalationJob) Run() {
+	Repo.EvaluateEscalations()
+}
+
+// escalationEntry is the scheduler entry of the escalation job, once added
+var (
+	escalationMu    sync.Mutex
+	escalationEntry cron.EntryID
+)
+
+// scheduleEscalations adds the escalation job to the scheduler, next to the check jobs,
+// unless it is there already
+func (repo *DBRepo) scheduleEscalations() {
+	escalationMu.Lock()
+	defer escalationMu.Unlock()
+
+	if repo.App.Scheduler.Entry(escalationEntry).Valid() {
+		return
+	}
+	id, err := repo.App.Scheduler.AddJob(escalationSchedule, escalationJob{})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	escalationEntry = id
+}
+
+// EvaluateEscalations notifies the escalation steps that are due for every open, that is
+// unacknowledged, incident whose service is still in problem. How far each incident has
+// been escalated is kept on the incident, so a restart carries on where it left off.
+func (repo *DBRepo) EvaluateEscalations() {
+	incidents, err := repo.DB.GetIncidentsByStatus(incidentOpen)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if len(incidents) == 0 {
+		return
+	}
+
+	users, err := repo.DB.AllUsers()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	now := time.Now()
+	policies := make(map[int]models.EscalationPolicy)
+
+	for _, i := range incidents {
+		h, err := repo.DB.GetHostByID(i.HostID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if h.EscalationPolicyID == 0 {
+			continue
+		}
+
+		hs, err := repo.DB.GetHostServiceByID(i.HostServiceID)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if hs.Status != "problem" {
+			continue
+		}
+		if _, ok := repo.maintenancePeriod(hs, now); ok {
+			continue
+		}
+
+		p, ok := policies[h.EscalationPolicyID]
+		if !ok {
+			p, err = repo.DB.GetEscalationPolicyByID(h.EscalationPolicyID)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			policies[p.ID] = p
+		}
+
+		due := escalation.Evaluate(p, i, now)
+		if len(due) == 0 {
+			continue
+		}
+		for _, d := range due {
+			repo.escalate(h, hs, i, p, d, users, now)
+		}
+
+		err = repo.DB.UpdateIncidentEscalation(i.ID, due[len(due)-1].Position, now)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// escalate notifies the users and channels of one escalation step about an incident,
+// and records it as an event of the incident
+func (repo *DBRepo) escalate(h models.Host, hs models.HostService, i models.Incident, p models.EscalationPolicy,
+	d escalation.Due, users []*models.User, now time.Time) {
+	open := helpers.FormatDuration(now.Sub(i.OpenedAt))
+
+	note := fmt.Sprintf("Escalated to step %d of %d of '%s' after %s.", d.Position, len(p.Steps), p.Name, open)
+	eventType := "escalated"
+	if d.Reminder {
+		note = fmt.Sprintf("Reminder: still unacknowledged after %s (step %d of %d of '%s').", open, d.Position, len(p.Steps), p.Name)
+		eventType = "reminder"
+	}
+
+	pm := repo.App.Preferences.Map()
+	n := notifiers.Notification{
+		HostID:        h.ID,
+		HostServiceID: hs.ID,
+		HostName:      h.HostName,
+		ServiceName:   hs.Service.ServiceName,
+		OldStatus:     hs.Status,
+		NewStatus:     hs.Status,
+		Message:       hs.LastMessage,
+		Link:          notifiers.IncidentLink(pm, i.ID),
+		Time:          now,
+		Escalation:    note,
+
+		PagerDutyRoutingKey: h.PagerDutyRoutingKey,
+		OnCall:              repo.onCallRecipients(h),
+	}
+
+	email := notifiers.EmailNotifier{Send: helpers.SendEmail}
+	for _, id := range escalation.UserIDs(d.Step) {
+		for _, u := range users {
+			if u.ID != id || u.UserActive != 1 || u.Email == "" {
+				continue
+			}
+			err := email.NotifyAddress(fmt.Sprintf("%s %s", u.FirstName, u.LastName), u.Email, n)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	notifiers.SendVia(pm, escalation.Channels(d.Step), n)
+
+	// escalations are skipped during maintenance, so the event never is in one
+	repo.saveEvent(h, hs, eventType, note, i.ID, false)
+}
+
+// AllEscalationPolicies lists escalation policies
+func (repo *DBRepo) AllEscalationPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := repo.DB.AllEscalationPolicies()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	userNames, err := repo.userNames()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// who each step notifies, by policy id, for the list
+	steps := make(map[int][]string)
+	for _, p := range policies {
+		for _, st := range p.Steps {
+			var who []string
+			for _, id := range escalation.UserIDs(st) {
+				if name, ok := userNames[id]; ok {
+					who = append(who, name)
+				}
+			}
+			who = append(who, escalation.Channels(st)...)
+			steps[p.ID] = append(steps[p.ID], fmt.Sprintf("after %d min: %s", st.DelayMinutes, strings.Join(who, ", ")))
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("policies", policies)
+	vars.Set("steps", steps)
+
+	err = helpers.RenderPage(w, r, "escalation-policies", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// OneEscalationPolicy displays the add/edit escalation policy page
+func (repo *DBRepo) OneEscalationPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	p := models.EscalationPolicy{Active: 1}
+	if id > 0 {
+		p, err = repo.DB.GetEscalationPolicyByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+	if len(p.Steps) == 0 {
+		p.Steps = []models.EscalationStep{{}}
+	}
+
+	users, err := repo.DB.AllUsers()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// the users and channels each step notifies, by step number, for the form
+	stepUsers := make(map[int]map[int]bool)
+	stepChannels := make(map[int]map[string]bool)
+	for n, s := range p.Steps {
+		stepUsers[n] = make(map[int]bool)
+		for _, id := range escalation.UserIDs(s) {
+			stepUsers[n][id] = true
+		}
+		stepChannels[n] = make(map[string]bool)
+		for _, c := range escalation.Channels(s) {
+			stepChannels[n][c] = true
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("policy", p)
+	vars.Set("users", users)
+	vars.Set("channels", notifiers.Names())
+	vars.Set("stepUsers", stepUsers)
+	vars.Set("stepChannels", stepChannels)
+
+	err = helpers.RenderPage(w, r, "escalation-policy", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostOneEscalationPolicy adds/edits an escalation policy. Steps are posted as
+// delay_<n>, users_<n> and channels_<n>, for n up to step_count; removed steps are
+// missing and skipped.
+func (repo *DBRepo) PostOneEscalationPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	var p models.EscalationPolicy
+	if id > 0 {
+		p, err = repo.DB.GetEscalationPolicyByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	p.Name = strings.TrimSpace(r.Form.Get("name"))
+	p.RepeatMinutes, _ = strconv.Atoi(r.Form.Get("repeat_minutes"))
+	p.Active, _ = strconv.Atoi(r.Form.Get("active"))
+
+	p.Steps = nil
+	count, _ := strconv.Atoi(r.Form.Get("step_count"))
+	for n := 0; n < count; n++ {
+		delay, ok := r.Form[fmt.Sprintf("delay_%d", n)]
+		if !ok {
+			continue
+		}
+
+		var s models.EscalationStep
+		s.DelayMinutes, _ = strconv.Atoi(delay[0])
+
+		var ids []int
+		for _, v := range r.Form[fmt.Sprintf("users_%d", n)] {
+			if uid, err := strconv.Atoi(v); err == nil {
+				ids = append(ids, uid)
+			}
+		}
+		s.UserIDs = escalation.JoinIDs(ids)
+		s.Channels = strings.Join(r.Form[fmt.Sprintf("channels_%d", n)], ",")
+
+		p.Steps = append(p.Steps, s)
+	}
+
+	if err := escalation.Validate(p, notifiers.Names()); err != nil {
+		repo.App.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+		return
+	}
+
+	if id > 0 {
+		err = repo.DB.UpdateEscalationPolicy(p)
+	} else {
+		_, err = repo.DB.InsertEscalationPolicy(p)
+	}
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/escalation-policies", http.StatusSeeOther)
+}
+
+// DeleteEscalationPolicy deletes an escalation policy
+func (repo *DBRepo) DeleteEscalationPolicy(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	_ = repo.DB.DeleteEscalationPolicy(id)
+	repo.App.Session.Put(r.Context(), "flash", "Escalation policy deleted")
+	http.Redirect(w, r, "/admin/escalation-policies", http.StatusSeeOther)
+}
+
+// userNames returns the full name of every user, by user id
+func (repo *DBRepo) userNames() (map[int]string, error) {
+	users, err := repo.DB.AllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string)
+	for _, u := range users {
+		names[u.ID] = fmt.Sprintf("%s %s", u.FirstName, u.LastName)
+	}
+	return names, nil
+}
@@ -7,14 +7,20 @@ import (
 	"net/http"
 	"runtime/debug"
 	"strconv"
+	"time"
 
 	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/checkers"
 	"github.com/brianmaksy/go-watch/internal/config"
 	"github.com/brianmaksy/go-watch/internal/driver"
+	"github.com/brianmaksy/go-watch/internal/executor"
 	"github.com/brianmaksy/go-watch/internal/helpers"
 	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/notifiers"
+	"github.com/brianmaksy/go-watch/internal/reports"
 	"github.com/brianmaksy/go-watch/internal/repository"
 	"github.com/brianmaksy/go-watch/internal/repository/dbrepo"
+	"github.com/brianmaksy/go-watch/internal/schedules"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -63,6 +69,16 @@ func (repo *DBRepo) AdminDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	vars.Set("hosts", allHosts)
+
+	// availability over the preset report ranges
+	vars.Set("uptimeError", "")
+	uptime, err := repo.overallUptimeByRange()
+	if err != nil {
+		log.Println(err)
+		vars.Set("uptimeError", uptimeErrorMessage)
+	}
+	vars.Set("uptime", uptime)
+
 	err = helpers.RenderPage(w, r, "dashboard", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
@@ -103,32 +119,67 @@ func (repo *DBRepo) PostSettings(w http.ResponseWriter, r *http.Request) {
 	prefMap["smtp_port"] = r.Form.Get("smtp_port")
 	prefMap["smtp_user"] = r.Form.Get("smtp_user")
 	prefMap["smtp_password"] = r.Form.Get("smtp_password")
+	prefMap["smtp_encryption"] = r.Form.Get("smtp_encryption")
 	prefMap["sms_enabled"] = r.Form.Get("sms_enabled")
 	prefMap["sms_provider"] = r.Form.Get("sms_provider")
 	prefMap["twilio_phone_number"] = r.Form.Get("twilio_phone_number")
 	prefMap["twilio_sid"] = r.Form.Get("twilio_sid")
 	prefMap["twilio_auth_token"] = r.Form.Get("twilio_auth_token")
+	prefMap["pagerduty_enabled"] = r.Form.Get("pagerduty_enabled")
+	prefMap["pagerduty_routing_key"] = r.Form.Get("pagerduty_routing_key")
+	prefMap["chat_enabled"] = r.Form.Get("chat_enabled")
+	prefMap["chat_webhook_url"] = r.Form.Get("chat_webhook_url")
+	prefMap["chat_channel"] = r.Form.Get("chat_channel")
+	prefMap["chat_flap_minutes"] = r.Form.Get("chat_flap_minutes")
+	for _, status := range []string{"problem", "warning", "healthy"} {
+		prefMap["chat_webhook_url_"+status] = r.Form.Get("chat_webhook_url_" + status)
+		prefMap["chat_channel_"+status] = r.Form.Get("chat_channel_" + status)
+	}
 	prefMap["smtp_from_email"] = r.Form.Get("smtp_from_email")
 	prefMap["smtp_from_name"] = r.Form.Get("smtp_from_name")
 	prefMap["notify_via_sms"] = r.Form.Get("notify_via_sms")
 	prefMap["notify_via_email"] = r.Form.Get("notify_via_email")
 	prefMap["sms_notify_number"] = r.Form.Get("sms_notify_number")
+	prefMap["check_connect_timeout"] = r.Form.Get("check_connect_timeout")
+	prefMap["check_tls_timeout"] = r.Form.Get("check_tls_timeout")
+	prefMap["check_timeout"] = r.Form.Get("check_timeout")
+	prefMap["check_max_redirects"] = r.Form.Get("check_max_redirects")
+	prefMap["check_user_agent"] = r.Form.Get("check_user_agent")
+	prefMap["check_proxy"] = r.Form.Get("check_proxy")
+	prefMap["flap_threshold"] = r.Form.Get("flap_threshold")
+	prefMap["flap_window_minutes"] = r.Form.Get("flap_window_minutes")
+	prefMap["check_workers"] = r.Form.Get("check_workers")
+	prefMap["check_workers_per_host"] = r.Form.Get("check_workers_per_host")
+	prefMap["check_jitter_seconds"] = r.Form.Get("check_jitter_seconds")
+	prefMap["check_results_retention_days"] = r.Form.Get("check_results_retention_days")
 
 	if r.Form.Get("sms_enabled") == "0" {
 		prefMap["notify_via_sms"] = "0"
 	}
 
-	err := repo.DB.InsertOrUpdateSitePreferences(prefMap)
+	// make sure the check client settings are usable before saving them
+	clientSettings := checkers.SettingsFromPreferences(prefMap)
+	err := clientSettings.Validate()
+	if err != nil {
+		app.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
+
+	err = repo.DB.InsertOrUpdateSitePreferences(prefMap)
 	if err != nil {
 		log.Println(err)
 		ClientError(w, r, http.StatusBadRequest)
 		return
 	}
 
-	// update app config
-	for k, v := range prefMap {
-		app.PreferenceMap[k] = v
+	// update app config, now that the settings are saved
+	app.Preferences.SetAll(prefMap)
+	err = checkers.Configure(clientSettings)
+	if err != nil {
+		log.Println(err)
 	}
+	app.Checks.Configure(executor.SettingsFromPreferences(prefMap))
 
 	app.Session.Put(r.Context(), "flash", "Changes saved")
 
@@ -139,6 +190,60 @@ func (repo *DBRepo) PostSettings(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SendTestSMS sends a text message with the Twilio settings currently in the settings
+// form, so they can be tried before saving, and returns JSON
+func (repo *DBRepo) SendTestSMS(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+	resp.OK = true
+	resp.Message = "Test message sent"
+
+	pm := make(map[string]string)
+	for _, k := range []string{"twilio_sid", "twilio_auth_token", "twilio_phone_number", "sms_notify_number"} {
+		pm[k] = r.Form.Get(k)
+	}
+
+	sms := notifiers.SMSNotifier{Client: &http.Client{Timeout: 10 * time.Second}}
+	err := sms.Send(pm, pm["sms_notify_number"], "go_watch: this is a test message")
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = err.Error()
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// SendTestChat posts a test message with the chat settings currently in the settings
+// form, to the webhook used for problems, and returns JSON
+func (repo *DBRepo) SendTestChat(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+	resp.OK = true
+	resp.Message = "Test message sent"
+
+	pm := make(map[string]string)
+	for k := range r.Form {
+		pm[k] = r.Form.Get(k)
+	}
+	url := pm["chat_webhook_url_problem"]
+	if url == "" {
+		url = pm["chat_webhook_url"]
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	err := notifiers.SendChatMessage(client, url, notifiers.SampleChatMessage(pm, "problem"))
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = err.Error()
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
 // AllHosts displays list of all hosts
 func (repo *DBRepo) AllHosts(w http.ResponseWriter, r *http.Request) {
 	// get all hosts from DB
@@ -147,9 +252,17 @@ func (repo *DBRepo) AllHosts(w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 		return
 	}
-	// send "hosts" data to template
+	deps, err := repo.DB.AllHostDependencies()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// send "hosts" data to template, ordered as a dependency tree if asked for
 	vars := make(jet.VarMap)
 	vars.Set("hosts", hosts)
+	vars.Set("tree", r.URL.Query().Get("view") == "tree")
+	vars.Set("rows", dependencyTree(hosts, deps))
 
 	err = helpers.RenderPage(w, r, "hosts", vars, nil)
 	if err != nil {
@@ -177,11 +290,69 @@ func (repo *DBRepo) Host(w http.ResponseWriter, r *http.Request) {
 
 	// NTS - tested: h.HostName = "Some host"
 	vars := make(jet.VarMap)
+
+	// availability of each service over the preset report ranges
+	uptime := make(map[int]map[string]reports.Summary)
+	vars.Set("uptimeError", "")
+	if h.ID > 0 {
+		var err error
+		uptime, err = repo.hostUptimeByRange(h)
+		if err != nil {
+			log.Println(err)
+			vars.Set("uptimeError", uptimeErrorMessage)
+		}
+	}
+	vars.Set("uptime", uptime)
+
+	// when each service is next due to be checked
+	nextRuns := make(map[int][]string)
+	for _, hs := range h.HostServices {
+		nextRuns[hs.ID] = repo.nextRunTimes(hs)
+	}
+	vars.Set("nextRuns", nextRuns)
+
+	// what this host depends on, and the hosts it can depend on
+	var deps []models.HostDependency
+	var parentHosts []models.Host
+	if h.ID > 0 {
+		var err error
+		deps, err = repo.DB.GetDependenciesForHost(h.ID)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		hosts, err := repo.DB.AllHosts()
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		for _, ph := range hosts {
+			if ph.ID != h.ID {
+				parentHosts = append(parentHosts, ph)
+			}
+		}
+	}
+	vars.Set("dependencies", deps)
+	vars.Set("parentHosts", parentHosts)
+
+	policies, err := repo.DB.AllEscalationPolicies()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	vars.Set("policies", policies)
+
+	oncallSchedules, err := repo.DB.AllOnCallSchedules()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	vars.Set("oncallSchedules", oncallSchedules)
 	vars.Set("host", h) // NTS - pass variable h to template. h only has non-null value if id > 0.
 	// nts - can access h in "pending" etc tabs too. Rather than getting another var which holds repo.DB.GetServicesByStatus
 	// also, the status there is for active ones.
 
-	err := helpers.RenderPage(w, r, "host", vars, nil)
+	err = helpers.RenderPage(w, r, "host", vars, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
@@ -211,6 +382,10 @@ func (repo *DBRepo) PostHost(w http.ResponseWriter, r *http.Request) {
 	h.IPV6 = r.Form.Get("ipv6")
 	h.Location = r.Form.Get("location")
 	h.OS = r.Form.Get("os")
+	h.PagerDutyRoutingKey = r.Form.Get("pagerduty_routing_key")
+	h.EscalationPolicyID, _ = strconv.Atoi(r.Form.Get("escalation_policy_id"))
+	h.OnCallScheduleID, _ = strconv.Atoi(r.Form.Get("oncall_schedule_id"))
+	wasActive := h.Active
 	active, _ := strconv.Atoi(r.Form.Get("active"))
 	// NTS - needed to enable returning 0/1. Set value to 1, because if not checked, form doesn't send value anyway.
 	// in case of unchecking, the value is "". -> somehow translates to 0.
@@ -223,6 +398,10 @@ func (repo *DBRepo) PostHost(w http.ResponseWriter, r *http.Request) {
 			log.Println(err)
 			return
 		}
+		// activating or deactivating a host starts or stops checking its services now
+		if h.Active != wasActive {
+			repo.updateHostSchedule(h)
+		}
 	} else {
 		// insert new host
 		newID, err := repo.DB.InsertHost(h)
@@ -300,6 +479,7 @@ func (repo *DBRepo) PostOneUser(w http.ResponseWriter, r *http.Request) {
 		u.FirstName = r.Form.Get("first_name")
 		u.LastName = r.Form.Get("last_name")
 		u.Email = r.Form.Get("email")
+		u.Phone = r.Form.Get("phone")
 		u.UserActive, _ = strconv.Atoi(r.Form.Get("user_active"))
 		err := repo.DB.UpdateUser(u)
 		if err != nil {
@@ -321,6 +501,7 @@ func (repo *DBRepo) PostOneUser(w http.ResponseWriter, r *http.Request) {
 		u.FirstName = r.Form.Get("first_name")
 		u.LastName = r.Form.Get("last_name")
 		u.Email = r.Form.Get("email")
+		u.Phone = r.Form.Get("phone")
 		u.UserActive, _ = strconv.Atoi(r.Form.Get("user_active"))
 		u.Password = []byte(r.Form.Get("password"))
 		u.AccessLevel = 3
@@ -412,14 +593,17 @@ func (repo *DBRepo) ToggleServiceForHost(w http.ResponseWriter, r *http.Request)
 	h, _ := repo.DB.GetHostByID(hostID)
 
 	// ad or remove host service from schedule
-	if active == 1 {
+	switch {
+	case active != 1:
+		// remove from schedule
+		repo.removeFromMonitorMap(hs)
+	case schedules.Paused(h, hs) != "":
+		// stays off the schedule until its host and service are active again
+	default:
 		// add to schedule
 		repo.pushScheduleChangedEvent(hs, "pending")
 		repo.pushStatusChangedEvent(h, hs, "pending")
 		repo.addToMonitorMap(hs)
-	} else {
-		// remove from schedule
-		repo.removeFromMonitorMap(hs)
 	}
 
 	// nts - write to client
@@ -444,7 +628,7 @@ func (repo *DBRepo) SetSystemPref(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// update value in application-wide config (to prevent toggle off in one page, but toggle is still on on another.)
-	repo.App.PreferenceMap["monitoring_live"] = prefValue
+	repo.App.Preferences.Set("monitoring_live", prefValue)
 
 	out, _ := json.MarshalIndent(resp, "", "	")
 	w.Header().Set("Content-Type", "application/json")
@@ -458,28 +642,17 @@ func (repo *DBRepo) ToggleMonitoring(w http.ResponseWriter, r *http.Request) {
 	if enabled == "1" {
 		// start monitoring
 		log.Println("Turning monitoring on")
-		repo.App.PreferenceMap["monitoring_live"] = "1" // since this is one off, not bother with js async functions
-		repo.StartMonitoring()
-		repo.App.Scheduler.Start()
+		repo.App.Preferences.Set("monitoring_live", "1") // since this is one off, not bother with js async functions
+		// only the leader runs the scheduler; a standby leaves it to the leader, which
+		// notices the change when it next renews its lease
+		if repo.App.Leader.IsLeader() {
+			repo.startScheduler()
+		}
 	} else {
 		// stop monitoring
 		log.Println("Turning monitoring off")
-		repo.App.PreferenceMap["monitoring_live"] = "0"
-		// remove all items in map from schedule (i.e. the value in the pair)
-		for _, x := range repo.App.MonitorMap {
-			repo.App.Scheduler.Remove(x) // NTS: _ is the key, x is the value -> for go looping.
-		}
-
-		// empty the monitor map (by deleting key as well, after del value above)
-		for k := range repo.App.MonitorMap {
-			delete(repo.App.MonitorMap, k) // map, then key.
-		}
-		// delete all entries from schedule - to make sure it's empty.
-		for _, i := range repo.App.Scheduler.Entries() {
-			repo.App.Scheduler.Remove(i.ID)
-		}
-
-		repo.App.Scheduler.Stop()
+		repo.App.Preferences.Set("monitoring_live", "0")
+		repo.stopScheduler()
 
 		data := make(map[string]string)
 		data["message"] = "Monitoring is off..."
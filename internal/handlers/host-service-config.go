@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/checkers"
+	"github.com/brianmaksy/go-watch/internal/httpheaders"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/schedules"
+)
+
+// nextRunCount is how many upcoming check times the host page shows for a service
+const nextRunCount = 5
+
+// SaveHostServiceConfig saves the check settings and schedule of a host service and
+// returns JSON
+// (called from the Configure panel in the Manage Services tab of host.jet)
+func (repo *DBRepo) SaveHostServiceConfig(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+	resp.OK = true
+
+	hostServiceID, _ := strconv.Atoi(r.Form.Get("host_service_id"))
+
+	hs, err := repo.DB.GetHostServiceByID(hostServiceID)
+	if err != nil {
+		log.Println(err)
+		resp.OK = false
+		resp.Message = "Host service not found"
+	}
+
+	if resp.OK {
+		hs.Config, err = checkConfigFromForm(r, hs.Config)
+		if err != nil {
+			resp.OK = false
+			resp.Message = err.Error()
+		}
+	}
+
+	spec := schedules.Spec(hs)
+	if resp.OK {
+		hs, err = scheduleFromForm(r, hs)
+		if err != nil {
+			resp.OK = false
+			resp.Message = err.Error()
+		}
+	}
+
+	if resp.OK {
+		// settings and schedule are saved together, so a failure leaves neither half saved
+		err = repo.DB.UpdateHostServiceConfig(hs)
+		if err != nil {
+			log.Println(err)
+			resp.OK = false
+			resp.Message = "Could not save settings"
+		}
+	}
+
+	if resp.OK {
+		// a new schedule takes effect now that it is saved, not once monitoring is next
+		// turned on
+		if schedules.Spec(hs) != spec {
+			repo.rescheduleHostService(hs)
+		}
+		resp.NextRuns = repo.nextRunTimes(hs)
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// checkConfigFromForm reads and validates the check settings posted from the host page
+func checkConfigFromForm(r *http.Request, cfg models.CheckConfig) (models.CheckConfig, error) {
+	port, err := optionalInt(r.Form.Get("port"))
+	if err != nil || port < 0 || port > 65535 {
+		return cfg, errors.New("port must be a number between 1 and 65535")
+	}
+	cfg.Port = port
+
+	cfg.DNSResolver = strings.TrimSpace(r.Form.Get("dns_resolver"))
+	cfg.DNSExpected = strings.TrimSpace(r.Form.Get("dns_expected"))
+	cfg.DNSRecordType = strings.ToUpper(r.Form.Get("dns_record_type"))
+	if cfg.DNSRecordType != "" && !inList(cfg.DNSRecordType, checkers.DNSRecordTypes) {
+		return cfg, errors.New("unsupported DNS record type")
+	}
+
+	cfg.HTTPPath = strings.TrimSpace(r.Form.Get("http_path"))
+	cfg.HTTPMethod = strings.ToUpper(r.Form.Get("http_method"))
+	if cfg.HTTPMethod != "" && !inList(cfg.HTTPMethod, checkers.HTTPMethods) {
+		return cfg, errors.New("unsupported HTTP method")
+	}
+
+	cfg.HTTPHeaders = strings.TrimSpace(r.Form.Get("http_headers"))
+	if _, err := httpheaders.Parse(cfg.HTTPHeaders); err != nil {
+		return cfg, err
+	}
+
+	cfg.HTTPBody = r.Form.Get("http_body")
+
+	cfg.ExpectedStatus = strings.TrimSpace(r.Form.Get("expected_status"))
+	if _, err := checkers.ParseStatusRanges(cfg.ExpectedStatus); err != nil {
+		return cfg, err
+	}
+
+	cfg.BodyMatch = r.Form.Get("body_match")
+	cfg.BodyMatchRegex, _ = strconv.Atoi(r.Form.Get("body_match_regex"))
+	cfg.BodyMatchNegate, _ = strconv.Atoi(r.Form.Get("body_match_negate"))
+	if cfg.BodyMatchRegex == 1 {
+		if _, err := regexp.Compile(cfg.BodyMatch); err != nil {
+			return cfg, errors.New("body match is not a valid regular expression")
+		}
+	}
+
+	cfg.WarnLatency, err = optionalInt(r.Form.Get("warn_latency_ms"))
+	if err != nil || cfg.WarnLatency < 0 {
+		return cfg, errors.New("warning latency must be a number of milliseconds")
+	}
+	cfg.CriticalLatency, err = optionalInt(r.Form.Get("critical_latency_ms"))
+	if err != nil || cfg.CriticalLatency < 0 {
+		return cfg, errors.New("critical latency must be a number of milliseconds")
+	}
+	if cfg.WarnLatency > 0 && cfg.CriticalLatency > 0 && cfg.WarnLatency >= cfg.CriticalLatency {
+		return cfg, errors.New("warning latency must be lower than critical latency")
+	}
+
+	cfg.FailAfter, err = optionalInt(r.Form.Get("fail_after"))
+	if err != nil || cfg.FailAfter < 0 {
+		return cfg, errors.New("fail after must be a number of checks")
+	}
+	cfg.RecoverAfter, err = optionalInt(r.Form.Get("recover_after"))
+	if err != nil || cfg.RecoverAfter < 0 {
+		return cfg, errors.New("recover after must be a number of checks")
+	}
+	if cfg.FailAfter == 0 {
+		cfg.FailAfter = 1
+	}
+	if cfg.RecoverAfter == 0 {
+		cfg.RecoverAfter = 1
+	}
+	cfg.RecheckSeconds, err = optionalInt(r.Form.Get("recheck_seconds"))
+	if err != nil || cfg.RecheckSeconds < 0 {
+		return cfg, errors.New("re-check interval must be a number of seconds")
+	}
+
+	return cfg, nil
+}
+
+// scheduleFromForm reads and validates the schedule posted from the host page: either
+// an interval, or a cron expression with an optional time zone
+func scheduleFromForm(r *http.Request, hs models.HostService) (models.HostService, error) {
+	hs.ScheduleCron = ""
+	hs.ScheduleTimeZone = ""
+
+	if r.Form.Get("schedule_kind") == "cron" {
+		hs.ScheduleCron = strings.TrimSpace(r.Form.Get("schedule_cron"))
+		hs.ScheduleTimeZone = strings.TrimSpace(r.Form.Get("schedule_time_zone"))
+		if hs.ScheduleCron == "" {
+			return hs, errors.New("please enter a cron expression")
+		}
+	} else {
+		number, err := strconv.Atoi(strings.TrimSpace(r.Form.Get("schedule_number")))
+		if err != nil {
+			return hs, errors.New("the interval must be a number")
+		}
+		hs.ScheduleNumber = number
+		hs.ScheduleUnit = r.Form.Get("schedule_unit")
+	}
+
+	return hs, schedules.Validate(hs)
+}
+
+// nextRunTimes returns when a host service is next due to be checked, for display. A
+// scheduled host service counts from the scheduler's own next run.
+func (repo *DBRepo) nextRunTimes(hs models.HostService) []string {
+	var runs []time.Time
+	var err error
+	if next := repo.App.Monitors.Next(hs.ID); !next.IsZero() {
+		runs, err = schedules.NextRunsFrom(hs, next, nextRunCount)
+	} else {
+		runs, err = schedules.NextRuns(hs, time.Now(), nextRunCount)
+	}
+	if err != nil {
+		return nil
+	}
+
+	var times []string
+	for _, t := range runs {
+		times = append(times, t.Format("Mon Jan 2 3:04:05 PM MST"))
+	}
+	return times
+}
+
+// optionalInt converts s to an int, treating an empty string as zero
+func optionalInt(s string) (int, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(strings.TrimSpace(s))
+}
+
+// inList reports whether s is one of list
+func inList(s string, list []string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// Incident statuses
+const (
+	incidentOpen         = "open"
+	incidentAcknowledged = "acknowledged"
+	incidentResolved     = "resolved"
+)
+
+// openIncident returns the unresolved incident of a host service; its ID is 0 if there
+// is none
+func (repo *DBRepo) openIncident(hs models.HostService) models.Incident {
+	i, err := repo.DB.GetOpenIncidentForHostService(hs.ID)
+	if err != nil {
+		log.Println(err)
+	}
+	return i
+}
+
+// startIncident opens an incident for a host service that has gone into problem, and
+// links the check results that led up to it: everything since the previous status
+// change, or since from if there was none
+func (repo *DBRepo) startIncident(h models.Host, hs models.HostService, from time.Time) models.Incident {
+	i := models.Incident{
+		HostID:        h.ID,
+		HostServiceID: hs.ID,
+		Title:         fmt.Sprintf("%s on %s is down", hs.Service.ServiceName, h.HostName),
+		Status:        incidentOpen,
+		OpenedAt:      time.Now(),
+	}
+
+	id, err := repo.DB.InsertIncident(i)
+	if err != nil {
+		log.Println(err)
+		return models.Incident{}
+	}
+	i.ID = id
+
+	yearOne := time.Date(0001, 2, 2, 0, 0, 0, 1, time.UTC)
+	if hs.LastCheck.After(yearOne) && hs.LastCheck.Before(from) {
+		from = hs.LastCheck
+	}
+	err = repo.DB.LinkCheckResultsToIncident(i.ID, hs.ID, from)
+	if err != nil {
+		log.Println(err)
+	}
+	return i
+}
+
+// AllIncidents lists incidents, optionally only those with one status
+func (repo *DBRepo) AllIncidents(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	incidents, err := repo.DB.AllIncidents(status)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("incidents", incidents)
+	vars.Set("status", status)
+
+	err = helpers.RenderPage(w, r, "incidents", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// OneIncident shows an incident with its notes, events and check results
+func (repo *DBRepo) OneIncident(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	i, err := repo.DB.GetIncidentByID(id)
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	notes, err := repo.DB.GetIncidentNotes(i.ID)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	events, err := repo.DB.GetEventsForIncident(i.ID)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	results, err := repo.DB.GetCheckResultsForIncident(i.ID)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// how long the incident lasted, or has lasted so far
+	end := time.Now()
+	if i.Status == incidentResolved {
+		end = i.ResolvedAt
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("incident", i)
+	vars.Set("notes", notes)
+	vars.Set("events", events)
+	vars.Set("results", results)
+	vars.Set("duration", end.Sub(i.OpenedAt))
+
+	err = helpers.RenderPage(w, r, "incident", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// AcknowledgeIncident marks an incident as acknowledged by the current user, which
+// stops further notifications about it
+func (repo *DBRepo) AcknowledgeIncident(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	userID := repo.App.Session.GetInt(r.Context(), "userID")
+
+	acknowledged, err := repo.DB.AcknowledgeIncident(id, userID)
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if !acknowledged {
+		// either there is no such incident, or it is no longer open
+		if _, err := repo.DB.GetIncidentByID(id); err != nil {
+			ClientError(w, r, http.StatusNotFound)
+			return
+		}
+		ClientError(w, r, http.StatusConflict)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Incident acknowledged")
+	http.Redirect(w, r, fmt.Sprintf("/admin/incidents/%d", id), http.StatusSeeOther)
+}
+
+// PostIncidentNote adds a note to an incident
+func (repo *DBRepo) PostIncidentNote(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	note := strings.TrimSpace(r.Form.Get("note"))
+	if note == "" {
+		repo.App.Session.Put(r.Context(), "error", "Please enter a note")
+		http.Redirect(w, r, fmt.Sprintf("/admin/incidents/%d", id), http.StatusSeeOther)
+		return
+	}
+
+	_, err := repo.DB.InsertIncidentNote(models.IncidentNote{
+		IncidentID: id,
+		UserID:     repo.App.Session.GetInt(r.Context(), "userID"),
+		Note:       note,
+	})
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Note added")
+	http.Redirect(w, r, fmt.Sprintf("/admin/incidents/%d", id), http.StatusSeeOther)
+}
+
+// PostIncidentResolution saves the resolution summary of an incident, and resolves it
+// if asked to
+func (repo *DBRepo) PostIncidentResolution(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	err := repo.DB.UpdateIncidentResolution(id, strings.TrimSpace(r.Form.Get("resolution")))
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if r.Form.Get("resolve") == "1" {
+		err = repo.DB.ResolveIncident(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, fmt.Sprintf("/admin/incidents/%d", id), http.StatusSeeOther)
+}
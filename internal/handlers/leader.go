@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/brianmaksy/go-watch/internal/checkers"
+	"github.com/brianmaksy/go-watch/internal/executor"
+)
+
+// LeaseName is the lease held by the instance that runs the scheduler, when several
+// instances share a database
+const LeaseName = "scheduler"
+
+// schedulerRunning is 1 while this instance runs the scheduler. It is set from
+// handlers and the leader election, and read by check workers, so it is only ever
+// loaded and stored atomically.
+var schedulerRunning int32
+
+// monitoring reports whether checks run on this instance: monitoring is on, and this
+// instance is the leader
+func (repo *DBRepo) monitoring() bool {
+	return atomic.LoadInt32(&schedulerRunning) == 1 && repo.App.Leader.IsLeader()
+}
+
+// startScheduler schedules every host service to monitor and starts the scheduler,
+// unless it is already running
+func (repo *DBRepo) startScheduler() {
+	if !atomic.CompareAndSwapInt32(&schedulerRunning, 0, 1) {
+		return
+	}
+	repo.StartMonitoring()
+	repo.App.Scheduler.Start()
+}
+
+// stopScheduler takes every job off the scheduler and stops it
+func (repo *DBRepo) stopScheduler() {
+	atomic.StoreInt32(&schedulerRunning, 0)
+
+	// remove all host services from schedule
+	repo.App.Monitors.RemoveAll()
+	// delete all entries from schedule - to make sure it's empty.
+	for _, i := range repo.App.Scheduler.Entries() {
+		repo.App.Scheduler.Remove(i.ID)
+	}
+
+	repo.App.Scheduler.Stop()
+}
+
+// LeaderElected starts the scheduler when this instance becomes the leader, if
+// monitoring is on. Preferences may have been saved on another instance while this one
+// stood by, so they are reloaded from the database first.
+func (repo *DBRepo) LeaderElected() {
+	if repo.reloadPreferences() && repo.App.Preferences.Get("monitoring_live") == "1" {
+		repo.startScheduler()
+	}
+}
+
+// LeaderRenewed picks up preferences saved on other instances since the lease was last
+// renewed, and starts or stops the scheduler if monitoring was turned on or off there
+func (repo *DBRepo) LeaderRenewed() {
+	if !repo.reloadPreferences() {
+		return
+	}
+	live := repo.App.Preferences.Get("monitoring_live") == "1"
+	running := atomic.LoadInt32(&schedulerRunning) == 1
+
+	switch {
+	case live && !running:
+		log.Println("Monitoring was turned on on another instance")
+		repo.startScheduler()
+	case running && !live:
+		log.Println("Monitoring was turned off on another instance")
+		repo.stopScheduler()
+	}
+}
+
+// LeaderDemoted stops the scheduler when this instance stops being the leader, so the
+// new leader is the only one running checks
+func (repo *DBRepo) LeaderDemoted() {
+	repo.stopScheduler()
+}
+
+// reloadPreferences puts the preferences saved in the database, where every instance
+// saves them, in use on this instance. The check client and executor are reconfigured
+// if their settings have changed. It reports false if the database could not be read.
+func (repo *DBRepo) reloadPreferences() bool {
+	preferences, err := repo.DB.AllPreferences()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	pm := make(map[string]string, len(preferences))
+	for _, p := range preferences {
+		pm[p.Name] = string(p.Preference)
+	}
+
+	old := repo.App.Preferences.Map()
+	repo.App.Preferences.SetAll(pm)
+
+	if s := checkers.SettingsFromPreferences(pm); s != checkers.SettingsFromPreferences(old) {
+		err = checkers.Configure(s)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	if s := executor.SettingsFromPreferences(pm); s != executor.SettingsFromPreferences(old) {
+		repo.App.Checks.Configure(s)
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/brianmaksy/go-watch/internal/config"
+	"github.com/brianmaksy/go-watch/internal/executor"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/repository"
+)
+
+// preferenceDB is a database holding only preferences, which every instance saves to.
+// Calling any other method panics.
+type preferenceDB struct {
+	repository.DatabaseRepo
+
+	mu    sync.Mutex
+	prefs map[string]string
+	down  bool
+}
+
+func (p *preferenceDB) AllPreferences() ([]models.Preference, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.down {
+		return nil, errors.New("database unavailable")
+	}
+	var preferences []models.Preference
+	for name, value := range p.prefs {
+		preferences = append(preferences, models.Preference{Name: name, Preference: []byte(value)})
+	}
+	return preferences, nil
+}
+
+// save saves preferences, as the settings page of another instance does
+func (p *preferenceDB) save(pm map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, v := range pm {
+		p.prefs[k] = v
+	}
+}
+
+// leaderRepo returns handlers running with the preferences in db, as saved when the
+// instance started, and the database shared with the other instances
+func leaderRepo(db *preferenceDB) *DBRepo {
+	pm := map[string]string{"identifier": "go-watch-a"}
+	for k, v := range db.prefs {
+		pm[k] = v
+	}
+
+	return &DBRepo{
+		App: &config.AppConfig{
+			Preferences: config.NewPreferences(pm),
+			Checks:      executor.New(executor.SettingsFromPreferences(pm), func(int) {}),
+		},
+		DB: db,
+	}
+}
+
+func TestLeaderPicksUpPreferencesSavedElsewhere(t *testing.T) {
+	tests := []struct {
+		name   string
+		leader func(repo *DBRepo)
+	}{
+		{"on election", (*DBRepo).LeaderElected},
+		{"on renewal", (*DBRepo).LeaderRenewed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &preferenceDB{prefs: map[string]string{
+				"monitoring_live":     "0",
+				"flap_window_minutes": "10",
+				"check_workers":       "10",
+			}}
+			repo := leaderRepo(db)
+
+			db.save(map[string]string{"flap_window_minutes": "30", "check_workers": "4"})
+			tt.leader(repo)
+
+			if got := repo.App.Preferences.Get("flap_window_minutes"); got != "30" {
+				t.Errorf("flap_window_minutes is %q, want 30", got)
+			}
+			if got := repo.App.Checks.Stats().Workers; got != 4 {
+				t.Errorf("executor has %d workers, want 4", got)
+			}
+			// preferences that are not saved in the database are kept
+			if got := repo.App.Preferences.Get("identifier"); got != "go-watch-a" {
+				t.Errorf("identifier is %q, want go-watch-a", got)
+			}
+		})
+	}
+}
+
+func TestLeaderKeepsPreferencesWhenDatabaseIsDown(t *testing.T) {
+	db := &preferenceDB{prefs: map[string]string{"monitoring_live": "0", "flap_window_minutes": "10"}}
+	repo := leaderRepo(db)
+
+	db.save(map[string]string{"flap_window_minutes": "30"})
+	db.down = true
+	repo.LeaderRenewed()
+
+	if got := repo.App.Preferences.Get("flap_window_minutes"); got != "10" {
+		t.Errorf("flap_window_minutes is %q, want 10 until the database can be read", got)
+	}
+}
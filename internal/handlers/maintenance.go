@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/maintenance"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// datetimeLocalLayout is the value format of an html datetime-local input
+const datetimeLocalLayout = "2006-01-02T15:04"
+
+// upcomingMaintenance is how far ahead the schedule page lists maintenance windows
+const upcomingMaintenance = 7 * 24 * time.Hour
+
+// maintenancePeriod returns the maintenance window a host service is in at t, if any
+func (repo *DBRepo) maintenancePeriod(hs models.HostService, t time.Time) (maintenance.Period, bool) {
+	windows, err := repo.DB.AllMaintenanceWindows()
+	if err != nil {
+		log.Println(err)
+		return maintenance.Period{}, false
+	}
+	return maintenance.InMaintenance(windows, hs.HostID, hs.ID, t)
+}
+
+// AllMaintenanceWindows lists maintenance windows
+func (repo *DBRepo) AllMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	windows, err := repo.DB.AllMaintenanceWindows()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	scopes, err := repo.maintenanceScopeNames()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// the current or next occurrence of each window, by window id
+	now := time.Now()
+	periods := make(map[int]maintenance.Period)
+	active := make(map[int]bool)
+	for _, mw := range windows {
+		if p, ok := maintenance.CurrentPeriod(mw, now); ok {
+			periods[mw.ID] = p
+			active[mw.ID] = true
+		} else if p, ok := maintenance.NextPeriod(mw, now); ok {
+			periods[mw.ID] = p
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("windows", windows)
+	vars.Set("scopes", scopes)
+	vars.Set("periods", periods)
+	vars.Set("active", active)
+
+	err = helpers.RenderPage(w, r, "maintenance-windows", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// OneMaintenanceWindow displays the add/edit maintenance window page
+func (repo *DBRepo) OneMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	mw := models.MaintenanceWindow{Scope: maintenance.ScopeAll, Active: 1}
+	if id > 0 {
+		mw, err = repo.DB.GetMaintenanceWindowByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("window", mw)
+	vars.Set("hosts", hosts)
+	vars.Set("startsAt", formatDatetimeLocal(mw.StartsAt))
+	vars.Set("endsAt", formatDatetimeLocal(mw.EndsAt))
+
+	err = helpers.RenderPage(w, r, "maintenance-window", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostOneMaintenanceWindow adds/edits a maintenance window
+func (repo *DBRepo) PostOneMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	var mw models.MaintenanceWindow
+	if id > 0 {
+		mw, err = repo.DB.GetMaintenanceWindowByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	mw.Name = strings.TrimSpace(r.Form.Get("name"))
+	mw.Scope = r.Form.Get("scope")
+	mw.HostID, mw.HostServiceID = 0, 0
+	switch mw.Scope {
+	case maintenance.ScopeHost:
+		mw.HostID, _ = strconv.Atoi(r.Form.Get("host_id"))
+	case maintenance.ScopeHostService:
+		mw.HostServiceID, _ = strconv.Atoi(r.Form.Get("host_service_id"))
+		if hs, err := repo.DB.GetHostServiceByID(mw.HostServiceID); err == nil {
+			mw.HostID = hs.HostID
+		}
+	}
+	mw.Active, _ = strconv.Atoi(r.Form.Get("active"))
+
+	mw.StartsAt, mw.EndsAt, mw.CronExpression, mw.DurationMinutes = time.Time{}, time.Time{}, "", 0
+	if r.Form.Get("kind") == "recurring" {
+		mw.CronExpression = strings.TrimSpace(r.Form.Get("cron_expression"))
+		mw.DurationMinutes, _ = strconv.Atoi(r.Form.Get("duration_minutes"))
+	} else {
+		mw.StartsAt = parseDatetimeLocal(r.Form.Get("starts_at"))
+		mw.EndsAt = parseDatetimeLocal(r.Form.Get("ends_at"))
+	}
+
+	if err := maintenance.Validate(mw); err != nil {
+		repo.App.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+		return
+	}
+
+	if id > 0 {
+		err = repo.DB.UpdateMaintenanceWindow(mw)
+	} else {
+		_, err = repo.DB.InsertMaintenanceWindow(mw)
+	}
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+func (repo *DBRepo) DeleteMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	_ = repo.DB.DeleteMaintenanceWindow(id)
+	repo.App.Session.Put(r.Context(), "flash", "Maintenance window deleted")
+	http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+}
+
+// maintenanceScopeNames returns a readable description of what each maintenance
+// window covers, by window id
+func (repo *DBRepo) maintenanceScopeNames() (map[int]string, error) {
+	windows, err := repo.DB.AllMaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		return nil, err
+	}
+	hostNames := make(map[int]string)
+	serviceNames := make(map[int]string)
+	for _, h := range hosts {
+		hostNames[h.ID] = h.HostName
+		for _, hs := range h.HostServices {
+			serviceNames[hs.ID] = fmt.Sprintf("%s on %s", hs.Service.ServiceName, h.HostName)
+		}
+	}
+
+	names := make(map[int]string)
+	for _, mw := range windows {
+		switch mw.Scope {
+		case maintenance.ScopeHost:
+			names[mw.ID] = hostNames[mw.HostID]
+		case maintenance.ScopeHostService:
+			names[mw.ID] = serviceNames[mw.HostServiceID]
+		default:
+			names[mw.ID] = "All hosts"
+		}
+	}
+	return names, nil
+}
+
+// formatDatetimeLocal formats t for a datetime-local input, leaving the zero time blank
+func formatDatetimeLocal(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(time.Local).Format(datetimeLocalLayout)
+}
+
+// parseDatetimeLocal parses the value of a datetime-local input in server time,
+// returning the zero time if it is blank or invalid
+func parseDatetimeLocal(s string) time.Time {
+	t, err := time.ParseInLocation(datetimeLocalLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
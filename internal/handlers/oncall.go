@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/notifiers"
+	"github.com/brianmaksy/go-watch/internal/oncall"
+	"github.com/go-chi/chi/v5"
+)
+
+// upcomingShifts is how many shifts the schedule page lists
+const upcomingShifts = 10
+
+// onCallRecipients returns who is on call for a host right now. It is empty if the host
+// has no schedule, nobody is on call, or the user on call is inactive, in which case
+// notifications go to the addresses in settings.
+func (repo *DBRepo) onCallRecipients(h models.Host) []notifiers.Recipient {
+	if h.OnCallScheduleID == 0 {
+		return nil
+	}
+
+	s, err := repo.DB.GetOnCallScheduleByID(h.OnCallScheduleID)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	sh, err := oncall.At(s, time.Now())
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	if sh.UserID == 0 {
+		return nil
+	}
+
+	u, err := repo.DB.GetUserById(sh.UserID)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	if u.UserActive != 1 {
+		return nil
+	}
+
+	return []notifiers.Recipient{{
+		Name:  fmt.Sprintf("%s %s", u.FirstName, u.LastName),
+		Email: u.Email,
+		Phone: u.Phone,
+	}}
+}
+
+// OnCall shows who is on call now and next for every schedule
+func (repo *DBRepo) OnCall(w http.ResponseWriter, r *http.Request) {
+	schedules, err := repo.DB.AllOnCallSchedules()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	userNames, err := repo.userNames()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// the current and next shift of each schedule, by schedule id, in the schedule's
+	// time zone
+	now := time.Now()
+	current := make(map[int]oncall.Shift)
+	next := make(map[int]oncall.Shift)
+	for _, s := range schedules {
+		shifts, err := oncall.Upcoming(s, now, 2)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		loc, _ := oncall.Location(s)
+		current[s.ID] = inLocation(shifts[0], loc)
+		if len(shifts) > 1 {
+			next[s.ID] = inLocation(shifts[1], loc)
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("schedules", schedules)
+	vars.Set("userNames", userNames)
+	vars.Set("current", current)
+	vars.Set("next", next)
+
+	err = helpers.RenderPage(w, r, "oncall", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// OneOnCallSchedule displays the add/edit on-call schedule page, with its upcoming
+// shifts and overrides
+func (repo *DBRepo) OneOnCallSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	s := models.OnCallSchedule{ShiftDays: 7}
+	if id > 0 {
+		s, err = repo.DB.GetOnCallScheduleByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	users, err := repo.DB.AllUsers()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	userNames, err := repo.userNames()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	// a new schedule gets one empty row in the rotation to start from
+	rotation := oncall.UserIDs(s)
+	if len(rotation) == 0 {
+		rotation = []int{0}
+	}
+
+	loc, err := oncall.Location(s)
+	if err != nil {
+		loc = time.Local
+	}
+
+	var shifts []oncall.Shift
+	var overrides []models.OnCallOverride
+	now := time.Now()
+	if s.ID > 0 {
+		upcoming, err := oncall.Upcoming(s, now, upcomingShifts)
+		if err != nil {
+			log.Println(err)
+		}
+		for _, sh := range upcoming {
+			shifts = append(shifts, inLocation(sh, loc))
+		}
+
+		// only overrides that still matter
+		for _, o := range s.Overrides {
+			if o.EndsAt.After(now) {
+				o.StartsAt = o.StartsAt.In(loc)
+				o.EndsAt = o.EndsAt.In(loc)
+				overrides = append(overrides, o)
+			}
+		}
+	}
+
+	rotationStart := ""
+	if !s.RotationStart.IsZero() {
+		rotationStart = s.RotationStart.In(loc).Format(datetimeLocalLayout)
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("schedule", s)
+	vars.Set("users", users)
+	vars.Set("userNames", userNames)
+	vars.Set("rotation", rotation)
+	vars.Set("rotationStart", rotationStart)
+	vars.Set("shifts", shifts)
+	vars.Set("overrides", overrides)
+
+	err = helpers.RenderPage(w, r, "oncall-schedule", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostOneOnCallSchedule adds/edits an on-call schedule. The rotation is posted as
+// rotation_user, once per user in order, and its start in the schedule's time zone.
+func (repo *DBRepo) PostOneOnCallSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	var s models.OnCallSchedule
+	if id > 0 {
+		s, err = repo.DB.GetOnCallScheduleByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.Name = strings.TrimSpace(r.Form.Get("name"))
+	s.TimeZone = strings.TrimSpace(r.Form.Get("time_zone"))
+	s.ShiftDays, _ = strconv.Atoi(r.Form.Get("shift_days"))
+
+	var ids []string
+	for _, v := range r.Form["rotation_user"] {
+		if uid, err := strconv.Atoi(v); err == nil && uid > 0 {
+			ids = append(ids, strconv.Itoa(uid))
+		}
+	}
+	s.UserIDs = strings.Join(ids, ",")
+
+	s.RotationStart = time.Time{}
+	if loc, err := oncall.Location(s); err == nil {
+		if t, err := time.ParseInLocation(datetimeLocalLayout, r.Form.Get("rotation_start"), loc); err == nil {
+			s.RotationStart = t
+		}
+	}
+
+	if err := oncall.Validate(s); err != nil {
+		repo.App.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+		return
+	}
+
+	if id > 0 {
+		err = repo.DB.UpdateOnCallSchedule(s)
+	} else {
+		id, err = repo.DB.InsertOnCallSchedule(s)
+	}
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, fmt.Sprintf("/admin/oncall/%d", id), http.StatusSeeOther)
+}
+
+// DeleteOnCallSchedule deletes an on-call schedule
+func (repo *DBRepo) DeleteOnCallSchedule(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	_ = repo.DB.DeleteOnCallSchedule(id)
+	repo.App.Session.Put(r.Context(), "flash", "On-call schedule deleted")
+	http.Redirect(w, r, "/admin/oncall", http.StatusSeeOther)
+}
+
+// PostOnCallOverride adds an override to a schedule. Its start and end are in the
+// schedule's time zone.
+func (repo *DBRepo) PostOnCallOverride(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	back := fmt.Sprintf("/admin/oncall/%d", id)
+
+	s, err := repo.DB.GetOnCallScheduleByID(id)
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+	loc, err := oncall.Location(s)
+	if err != nil {
+		loc = time.Local
+	}
+
+	o := models.OnCallOverride{OnCallScheduleID: s.ID}
+	o.UserID, _ = strconv.Atoi(r.Form.Get("user_id"))
+	if t, err := time.ParseInLocation(datetimeLocalLayout, r.Form.Get("starts_at"), loc); err == nil {
+		o.StartsAt = t
+	}
+	if t, err := time.ParseInLocation(datetimeLocalLayout, r.Form.Get("ends_at"), loc); err == nil {
+		o.EndsAt = t
+	}
+
+	if err := oncall.ValidateOverride(o); err != nil {
+		repo.App.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	_, err = repo.DB.InsertOnCallOverride(o)
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Override added")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// DeleteOnCallOverride removes an override from a schedule
+func (repo *DBRepo) DeleteOnCallOverride(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	overrideID, _ := strconv.Atoi(chi.URLParam(r, "overrideID"))
+	_ = repo.DB.DeleteOnCallOverride(id, overrideID)
+	repo.App.Session.Put(r.Context(), "flash", "Override removed")
+	http.Redirect(w, r, fmt.Sprintf("/admin/oncall/%d", id), http.StatusSeeOther)
+}
+
+// inLocation returns a shift with its times in loc, for display
+func inLocation(sh oncall.Shift, loc *time.Location) oncall.Shift {
+	if !sh.Start.IsZero() {
+		sh.Start = sh.Start.In(loc)
+	}
+	if !sh.End.IsZero() {
+		sh.End = sh.End.In(loc)
+	}
+	return sh
+}
@@ -6,20 +6,15 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/brianmaksy/go-watch/internal/certificateutils"
+	"github.com/brianmaksy/go-watch/internal/checkers"
 	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/notifiers"
+	"github.com/brianmaksy/go-watch/internal/schedules"
 	"github.com/go-chi/chi/v5"
 )
 
-const (
-	HTTP           = 1
-	HTTPS          = 2
-	SSLCertificate = 3
-)
-
 type jsonResp struct {
 	OK            bool      `json:"ok"`
 	Message       string    `json:"message"`
@@ -29,11 +24,17 @@ type jsonResp struct {
 	OldStatus     string    `json:"old_status"`
 	NewStatus     string    `json:"new_status"`
 	LastCheck     time.Time `json:"last_check"`
+	NextRuns      []string  `json:"next_runs,omitempty"`
 }
 
 // ScheduledCheck performs a scheduled check on a host service by id
 // nts - this is run in the cron package via scheduleID, err := app.Scheduler.AddJob(schedule, j)? in start-mon.go
 func (repo *DBRepo) ScheduledCheck(hostServiceID int) {
+	// checks still queued when monitoring was turned off are dropped
+	if !repo.monitoring() {
+		return
+	}
+
 	log.Println("***** Running check for", hostServiceID)
 
 	// get host and hostservice
@@ -48,6 +49,14 @@ func (repo *DBRepo) ScheduledCheck(hostServiceID int) {
 		return
 	}
 
+	// a host service paused or deactivated since it was scheduled is taken off the schedule
+	if hs.Active != 1 || schedules.Paused(h, hs) != "" {
+		log.Println("Not checking", hs.Service.ServiceName, "on", h.HostName, "as it is paused")
+		hs.HostName = h.HostName
+		repo.removeFromMonitorMap(hs)
+		return
+	}
+
 	// tests the service
 	newStatus, msg := repo.testServiceForHost(h, hs)
 
@@ -111,44 +120,40 @@ func (repo *DBRepo) TestCheck(w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 		okay = false
 	}
-	// test the service
-	newStatus, msg := repo.testServiceForHost(h, hs)
 
-	// broadcast service status changed event (take service from old status tab to new server's tab)
-	if newStatus != hs.Status {
-		repo.pushStatusChangedEvent(h, hs, newStatus)
-		event := models.Event{
-			HostServiceID: hs.ServiceID,
-			EventType:     newStatus,
-			HostID:        h.ID,
-			ServiceName:   hs.Service.ServiceName,
-			HostName:      h.HostName,
-			Message:       msg,
-			CreatedAt:     time.Time{},
-			UpdatedAt:     time.Time{},
-		}
+	// paused host services are not checked, not even on demand
+	paused := schedules.Paused(h, hs)
 
-		err := repo.DB.InsertEvent(event)
+	var newStatus, msg string
+	if okay && paused == "" {
+		// test the service
+		newStatus, msg = repo.testServiceForHost(h, hs)
+
+		// testServiceForHost has already broadcast and saved the status change event, if any
+
+		// update the host service in the DB with status (if changed) and last check
+		hs.Status = newStatus
+		hs.LastMessage = msg
+		hs.LastCheck = time.Now()
+		hs.UpdatedAt = time.Now()
+
+		err = repo.DB.UpdateHostService(hs) // nts - overwriting DB values.
 		if err != nil {
 			log.Println(err)
+			okay = false
 		}
 	}
 
-	// update the host service in the DB with status (if changed) and last check
-	hs.Status = newStatus
-	hs.LastMessage = msg
-	hs.LastCheck = time.Now()
-	hs.UpdatedAt = time.Now()
-
-	err = repo.DB.UpdateHostService(hs) // nts - overwriting DB values.
-	if err != nil {
-		log.Println(err)
-		okay = false
-	}
-
 	var resp jsonResp
 	// create json response
-	if okay {
+	switch {
+	case !okay:
+		resp.OK = false
+		resp.Message = "Something went wrong"
+	case paused != "":
+		resp.OK = false
+		resp.Message = "Checks are paused: " + paused
+	default:
 		resp = jsonResp{
 			OK:            true,
 			Message:       msg,
@@ -159,9 +164,6 @@ func (repo *DBRepo) TestCheck(w http.ResponseWriter, r *http.Request) {
 			NewStatus:     newStatus,
 			LastCheck:     time.Now(),
 		}
-	} else {
-		resp.OK = false
-		resp.Message = "Something went wrong"
 	}
 
 	// send json to client
@@ -172,49 +174,146 @@ func (repo *DBRepo) TestCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 func (repo *DBRepo) testServiceForHost(h models.Host, hs models.HostService) (string, string) {
-	var msg, newStatus string
+	var res checkers.Result
+	started := time.Now()
+
+	// look up the checker by the service's type name, rather than its id
+	checker, ok := checkers.Get(hs.Service.ServiceType)
+	if ok {
+		res = checker.Check(h, hs)
+	} else {
+		res.Message = fmt.Sprintf("no checker registered for service type '%s'", hs.Service.ServiceType)
+		res.Status = "problem"
+		res.ErrorClass = checkers.ErrorConfig
+	}
+	// a failure behind a parent that is down is recorded as unreachable
+	if res.Status == "problem" {
+		if parent := repo.downParent(hs); parent != "" {
+			res.Status = "unreachable"
+			res.Message = fmt.Sprintf("%s (unreachable: %s)", res.Message, parent)
+		}
+	}
 
-	switch hs.ServiceID {
-	case HTTP:
-		msg, newStatus = testHTTPForHost(h.URL)
-		break
+	// checks still run during maintenance, but their results are flagged. The window
+	// and the open incident are looked up once per run, and passed on from here.
+	period, inMaintenance := repo.maintenancePeriod(hs, time.Now())
 
-	case HTTPS:
-		msg, newStatus = testHTTPSForHost(h.URL)
-		break
+	// keep a record of every run, not just status changes, linked to the open incident
+	incident := repo.openIncident(hs)
+	repo.recordCheckResult(hs, res, inMaintenance, incident.ID)
 
-	case SSLCertificate:
-		msg, newStatus = testSSLForHost(h.URL)
-		break
-	}
+	// the status only changes once the new one is confirmed, and not while flapping
+	newStatus, msg := repo.confirmStatus(h, hs, res.Status, res.Message)
 
 	// broadcast to clients if appropriate
 	if hs.Status != newStatus {
+		if newStatus == "problem" && incident.ID == 0 {
+			incident = repo.startIncident(h, hs, started)
+		}
+
 		repo.pushStatusChangedEvent(h, hs, newStatus)
-		// save event
-		event := models.Event{
-			HostServiceID: hs.ServiceID,
-			EventType:     newStatus,
-			HostID:        h.ID,
-			ServiceName:   hs.Service.ServiceName,
-			HostName:      h.HostName,
-			Message:       msg,
-			CreatedAt:     time.Time{},
-			UpdatedAt:     time.Time{},
+		repo.saveEvent(h, hs, newStatus, msg, incident.ID, inMaintenance)
+
+		// if appropriate, send email, SMS or webhook notifications. Maintenance only holds
+		// back bad news: a recovery still goes out, so anything raised before the window
+		// (a PagerDuty incident, say) is resolved.
+		switch {
+		case inMaintenance && newStatus != "healthy":
+			log.Printf("%s on %s is in maintenance window '%s', not notifying", hs.Service.ServiceName, h.HostName, period.Window.Name)
+		case newStatus == "unreachable":
+			log.Printf("%s on %s is unreachable behind a parent, not notifying", hs.Service.ServiceName, h.HostName)
+		case incident.Status == incidentAcknowledged && newStatus != "healthy":
+			log.Printf("incident %d for %s on %s is acknowledged, not notifying", incident.ID, hs.Service.ServiceName, h.HostName)
+		default:
+			repo.notifyStatusChanged(h, hs, newStatus, msg)
 		}
 
-		err := repo.DB.InsertEvent(event)
-		if err != nil {
-			log.Println(err)
+		// recovery closes the incident
+		if newStatus == "healthy" && incident.ID > 0 {
+			err := repo.DB.ResolveIncident(incident.ID)
+			if err != nil {
+				log.Println(err)
+			}
 		}
 	}
 	repo.pushScheduleChangedEvent(hs, newStatus)
 
-	// if appropriate, send email or SMS message.
-
 	return newStatus, msg
 }
 
+// saveEvent records an event for a host service, flagged if it is in maintenance and
+// linked to its incident, if it has one (incidentID is 0 if not)
+func (repo *DBRepo) saveEvent(h models.Host, hs models.HostService, eventType, msg string, incidentID int, inMaintenance bool) {
+	event := models.Event{
+		HostServiceID: hs.ID,
+		EventType:     eventType,
+		HostID:        h.ID,
+		ServiceName:   hs.Service.ServiceName,
+		HostName:      h.HostName,
+		Message:       msg,
+		IncidentID:    incidentID,
+	}
+	if inMaintenance {
+		event.Maintenance = 1
+	}
+
+	err := repo.DB.InsertEvent(event)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// recordEvent records an event for a host service outside the status change of a check
+// run, looking up its open incident and maintenance window itself. It is for events
+// that are rare, such as a service starting or stopping flapping.
+func (repo *DBRepo) recordEvent(h models.Host, hs models.HostService, eventType, msg string) {
+	_, inMaintenance := repo.maintenancePeriod(hs, time.Now())
+	repo.saveEvent(h, hs, eventType, msg, repo.openIncident(hs).ID, inMaintenance)
+}
+
+// notifyStatusChanged sends a notification through every enabled notifier, addressed
+// to whoever is on call for the host. Each notifier decides which transitions it
+// cares about.
+func (repo *DBRepo) notifyStatusChanged(h models.Host, hs models.HostService, newStatus, msg string) {
+	pm := repo.App.Preferences.Map()
+	notifiers.Send(pm, notifiers.Notification{
+		HostID:        h.ID,
+		HostServiceID: hs.ID,
+		HostName:      h.HostName,
+		ServiceName:   hs.Service.ServiceName,
+		OldStatus:     hs.Status,
+		NewStatus:     newStatus,
+		Message:       msg,
+		Link:          notifiers.HostLink(pm, h.ID),
+		Time:          time.Now(),
+
+		PagerDutyRoutingKey: h.PagerDutyRoutingKey,
+		OnCall:              repo.onCallRecipients(h),
+	})
+}
+
+// recordCheckResult saves the outcome of a check run to the check history
+func (repo *DBRepo) recordCheckResult(hs models.HostService, res checkers.Result, inMaintenance bool, incidentID int) {
+	cr := models.CheckResult{
+		HostServiceID: hs.ID,
+		HostID:        hs.HostID,
+		Status:        res.Status,
+		LatencyMS:     int(res.Latency / time.Millisecond),
+		Message:       res.Message,
+		ErrorClass:    res.ErrorClass,
+		IncidentID:    incidentID,
+		CheckedAt:     time.Now(),
+	}
+	if inMaintenance {
+		cr.Maintenance = 1
+	}
+
+	err := repo.DB.InsertCheckResult(cr)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func (repo *DBRepo) pushStatusChangedEvent(h models.Host, hs models.HostService, newStatus string) {
 	yearOne := time.Date(0001, 2, 2, 0, 0, 0, 1, time.UTC) // nts - changed to feb, because if set to all one, then may actually be after this! (day light saving?!)
 
@@ -244,8 +343,8 @@ func (repo *DBRepo) pushScheduleChangedEvent(hs models.HostService, newStatus st
 	data["host_id"] = strconv.Itoa(hs.HostID)
 
 	// nts - .Next = next scheduled cron job.
-	if app.Scheduler.Entry(repo.App.MonitorMap[hs.ID]).Next.After(yearOne) {
-		data["next_run"] = repo.App.Scheduler.Entry(repo.App.MonitorMap[hs.ID]).Next.Format("2006-01-02 3:04:05 PM")
+	if next := repo.App.Monitors.Next(hs.ID); next.After(yearOne) {
+		data["next_run"] = next.Format("2006-01-02 3:04:05 PM")
 	} else {
 		data["next_run"] = "Pending"
 	}
@@ -256,7 +355,7 @@ func (repo *DBRepo) pushScheduleChangedEvent(hs models.HostService, newStatus st
 	}
 	data["host"] = hs.HostName
 	data["service"] = hs.Service.ServiceName
-	data["schedule"] = fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit) // nts - data type is a formatted string
+	data["schedule"] = schedules.Describe(hs) // nts - data type is a formatted string
 	data["status"] = newStatus
 	data["icon"] = hs.Service.Icon
 
@@ -264,127 +363,88 @@ func (repo *DBRepo) pushScheduleChangedEvent(hs models.HostService, newStatus st
 	// nts - rebroadcast after change?
 }
 
-func testHTTPForHost(url string) (string, string) {
-	// strip suffix
-	if strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	url = strings.Replace(url, "https://", "http://", -1) // -1 or smaller means no lim on num of replacements
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Sprintf("%s - %s", url, "error connecting"), "problem"
-	}
-	defer resp.Body.Close() // nts - otherwise get mem leak
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Sprintf("%s - %s", url, resp.Status), "problem"
-	}
-	return fmt.Sprintf("%s - %s", url, resp.Status), "healthy"
-
-}
-
-func testHTTPSForHost(url string) (string, string) {
-	// strip suffix
-	if strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	url = strings.Replace(url, "http://", "https://", -1) // -1 or smaller means no lim on num of replacements
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Sprintf("%s - %s", url, "error connecting"), "problem"
-	}
-	defer resp.Body.Close() // nts - otherwise get mem leak
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Sprintf("%s - %s", url, resp.Status), "problem"
-	}
-	return fmt.Sprintf("%s - %s", url, resp.Status), "healthy"
-
-}
-
-func scanHost(hostname string, certDetailsChannel chan certificateutils.CertificateDetails, errorsChannel chan error) {
-
-	res, err := certificateutils.GetCertificateDetails(hostname, 10)
-	if err != nil {
-		errorsChannel <- err
-	} else {
-		certDetailsChannel <- res
-	}
-}
-
-func testSSLForHost(url string) (string, string) {
-	if strings.HasPrefix(url, "https://") {
-		url = strings.Replace(url, "https://", "", -1)
-	}
-	if strings.HasPrefix(url, "http://") {
-		url = strings.Replace(url, "http://", "", -1)
-	}
-
-	var certDetailsChannel chan certificateutils.CertificateDetails
-	var errorsChannel chan error
-	certDetailsChannel = make(chan certificateutils.CertificateDetails, 1)
-	errorsChannel = make(chan error, 1)
-
-	var msg, newStatus string
-
-	scanHost(url, certDetailsChannel, errorsChannel)
-
-	// nts - for loop with two var declared. len(certDetailsChannel) doesn't change.
-	for i, certDetailsInQueue := 0, len(certDetailsChannel); i < certDetailsInQueue; i++ {
-		certDetails := <-certDetailsChannel
-		certificateutils.CheckExpirationStatus(&certDetails, 30)
-
-		if certDetails.ExpiringSoon {
-
-			if certDetails.DaysUntilExpiration < 7 {
-				msg = certDetails.Hostname + " expiring in " + strconv.Itoa(certDetails.DaysUntilExpiration) + " days"
-				newStatus = "problem"
-			} else {
-				msg = certDetails.Hostname + " expiring in " + strconv.Itoa(certDetails.DaysUntilExpiration) + " days"
-				newStatus = "warning"
-			}
+func (repo *DBRepo) addToMonitorMap(hs models.HostService) {
+	if repo.monitoring() {
+		// paused host services stay off the schedule
+		h, err := repo.DB.GetHostByID(hs.HostID)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if schedules.Paused(h, hs) != "" {
+			return
+		}
 
-		} else {
-			msg = certDetails.Hostname + " expiring in " + strconv.Itoa(certDetails.DaysUntilExpiration) + " days"
-			newStatus = "healthy"
+		j := newJob(hs)
+		_, err = repo.App.Monitors.Add(hs.ID, schedules.Spec(hs), j)
+		if err != nil {
+			log.Println(err)
+			return
 		}
+
+		repo.pushScheduledEvent(hs)
 	}
-	return msg, newStatus
 }
 
-func (repo *DBRepo) addToMonitorMap(hs models.HostService) {
-	if repo.App.PreferenceMap["monitoring_live"] == "1" {
-		var j job
-		j.HostServiceID = hs.ID
-		scheduleID, err := repo.App.Scheduler.AddJob(fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit), j)
+// rescheduleHostService puts a monitored host service on its new schedule straight
+// away, rather than once monitoring is next turned on
+func (repo *DBRepo) rescheduleHostService(hs models.HostService) {
+	if repo.monitoring() && repo.App.Monitors.Scheduled(hs.ID) {
+		j := newJob(hs)
+		_, err := repo.App.Monitors.Reschedule(hs.ID, schedules.Spec(hs), j)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 
-		repo.App.MonitorMap[hs.ID] = scheduleID
-		data := make(map[string]string)
-		data["message"] = "scheduling"
-		data["host_service_id"] = strconv.Itoa(hs.ID)
-		data["next_run"] = "Pending"
-		data["service"] = hs.Service.ServiceName
-		data["host"] = hs.HostName
-		data["last_run"] = hs.LastCheck.Format("2006-01-02 3:04:05 PM")
-		data["schedule"] = fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit)
-
-		repo.broadcastMessage("public-channel", "schedule-changed-event", data)
+		repo.pushScheduledEvent(hs)
 	}
 }
 
-// **nts - still has problem of duplication, and when removing, doesn't remove original entry added in schedule.go
+// removeFromMonitorMap stops checking a host service on its schedule
 func (repo *DBRepo) removeFromMonitorMap(hs models.HostService) {
-	if repo.App.PreferenceMap["monitoring_live"] == "1" {
-		repo.App.Scheduler.Remove(repo.App.MonitorMap[hs.ID])
+	if repo.monitoring() && repo.App.Monitors.Remove(hs.ID) {
 		data := make(map[string]string)
 		data["host_service_id"] = strconv.Itoa(hs.ID)
 		repo.broadcastMessage("public-channel", "schedule-item-removed-event", data)
+	}
+}
+
+// pushScheduledEvent broadcasts that a host service has been (re)scheduled, so open
+// schedule pages show it on its current schedule
+func (repo *DBRepo) pushScheduledEvent(hs models.HostService) {
+	yearOne := time.Date(0001, 2, 2, 0, 0, 0, 1, time.UTC)
+	data := make(map[string]string)
+	data["message"] = "scheduling"
+	data["host_service_id"] = strconv.Itoa(hs.ID)
+	if next := repo.App.Monitors.Next(hs.ID); next.After(yearOne) {
+		data["next_run"] = next.Format("2006-01-02 3:04:05 PM")
+	} else {
+		data["next_run"] = "Pending"
+	}
+	data["service"] = hs.Service.ServiceName
+	data["host"] = hs.HostName
+	if hs.LastCheck.After(yearOne) {
+		data["last_run"] = hs.LastCheck.Format("2006-01-02 3:04:05 PM")
+	} else {
+		data["last_run"] = "Pending"
+	}
+	data["schedule"] = schedules.Describe(hs)
+	data["status"] = hs.Status
+	data["icon"] = hs.Service.Icon
 
+	repo.broadcastMessage("public-channel", "schedule-changed-event", data)
+}
+
+// updateHostSchedule adds the active services of an active host to the schedule, and
+// removes all of its services when it is inactive, which pauses them
+func (repo *DBRepo) updateHostSchedule(h models.Host) {
+	for _, hs := range h.HostServices {
+		hs.HostName = h.HostName
+		if hs.Active == 1 && schedules.Paused(h, hs) == "" {
+			repo.addToMonitorMap(hs)
+		} else {
+			repo.removeFromMonitorMap(hs)
+		}
 	}
 }
@@ -1,14 +1,16 @@
 package handlers
 
 import (
-	"fmt"
 	"log"
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/maintenance"
 	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/schedules"
 )
 
 type ByHost []models.Schedule
@@ -27,23 +29,33 @@ func (a ByHost) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (repo *DBRepo) ListEntries(w http.ResponseWriter, r *http.Request) {
 	var items []models.Schedule
 
-	// NTS - not from DB, but monitormap from app
-	for k, v := range repo.App.MonitorMap {
+	windows, err := repo.DB.AllMaintenanceWindows()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	now := time.Now()
+
+	// NTS - not from DB, but the monitor registry from app
+	for _, m := range repo.App.Monitors.List() {
 		var item models.Schedule
-		// item.ID = k // nts - the teacher is wrong! should get key of host_services
-		item.EntryID = v
-		item.Entry = app.Scheduler.Entry(v)
+		item.EntryID = m.EntryID
+		item.Entry = m.Entry
 
-		hs, err := repo.DB.GetHostServiceByID(k)
+		hs, err := repo.DB.GetHostServiceByID(m.HostServiceID)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 		// log.Printf("%s", hs.HostName)
-		item.ScheduleText = fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit)
+		item.ScheduleText = schedules.Describe(hs)
 		item.LastRunFromHS = hs.LastCheck
 		item.Host = hs.HostName
 		item.Service = hs.Service.ServiceName
+		item.HostServiceID = hs.ID
+		if p, ok := maintenance.InMaintenance(windows, hs.HostID, hs.ID, now); ok {
+			item.Maintenance = p.Window.Name
+		}
 		items = append(items, item)
 		// nts - to sort []. Since Map doesn't sort. Not too easy to do
 		// log.Printf("%s", hs.HostName)
@@ -56,10 +68,20 @@ func (repo *DBRepo) ListEntries(w http.ResponseWriter, r *http.Request) {
 	// sort the slice
 	sort.Sort(ByHost(items)) // the three items req by go (?) nts - see ByHost(items) logic for slice.
 
+	scopes, err := repo.maintenanceScopeNames()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
 	data := make(jet.VarMap)
 	data.Set("items", items)
+	data.Set("maintenance", maintenance.ActiveAndUpcoming(windows, now, upcomingMaintenance))
+	data.Set("maintenanceScopes", scopes)
+	data.Set("now", now)
+	data.Set("queue", repo.App.Checks.Stats())
 
-	err := helpers.RenderPage(w, r, "schedule", data, nil)
+	err = helpers.RenderPage(w, r, "schedule", data, nil)
 	if err != nil {
 		printTemplateError(w, err)
 	}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+)
+
+// AllServices lists the services hosts can be checked for
+func (repo *DBRepo) AllServices(w http.ResponseWriter, r *http.Request) {
+	services, err := repo.DB.AllServices()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("services", services)
+
+	err = helpers.RenderPage(w, r, "services", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// ToggleService activates or deactivates a service on every host, and adds it to or
+// removes it from the schedule straight away. Returns JSON.
+func (repo *DBRepo) ToggleService(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+	resp.OK = true
+
+	serviceID, _ := strconv.Atoi(r.Form.Get("service_id"))
+	active, _ := strconv.Atoi(r.Form.Get("active"))
+
+	err := repo.DB.UpdateServiceActive(serviceID, active)
+	if err != nil {
+		resp.OK = false
+		resp.Message = "Could not save changes"
+	}
+
+	if resp.OK {
+		if active == 1 {
+			// the host services that would have been checked, had the service been active
+			hostServices, err := repo.DB.GetServicesToMonitor()
+			if err != nil {
+				log.Println(err)
+			}
+			for _, hs := range hostServices {
+				if hs.ServiceID == serviceID {
+					repo.addToMonitorMap(hs)
+				}
+			}
+		} else {
+			for _, m := range repo.App.Monitors.List() {
+				hs, err := repo.DB.GetHostServiceByID(m.HostServiceID)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				if hs.ServiceID == serviceID {
+					repo.removeFromMonitorMap(hs)
+				}
+			}
+		}
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
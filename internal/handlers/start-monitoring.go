@@ -1,27 +1,41 @@
 package handlers
 
 import (
-	"fmt"
 	"log"
 	"strconv"
 	"time"
+
+	"github.com/brianmaksy/go-watch/internal/executor"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/schedules"
 )
 
 type job struct {
 	HostServiceID int
+	HostID        int
+	Period        time.Duration
+}
+
+// newJob returns the scheduler job that checks a host service
+func newJob(hs models.HostService) job {
+	return job{
+		HostServiceID: hs.ID,
+		HostID:        hs.HostID,
+		Period:        schedules.Period(hs, time.Now()),
+	}
 }
 
 // nts - not a pointer receiver!
 func (j job) Run() {
 	// to put: unit of work to be performed
-	Repo.ScheduledCheck(j.HostServiceID) // access to ScheduledCheck (which is in handlers package (perform-check.go)
-	// via Repo in caps declared in handlers.go.
-	// cf lower case r for repo *DBRepo methods, this method belongs to job struct. Not DBRepo struct.
+	// the check itself runs on the executor's worker pool, which calls Repo.ScheduledCheck
+	// (in perform-checks.go) once a worker, and a slot for the host, are free.
+	app.Checks.Submit(executor.Task{HostServiceID: j.HostServiceID, HostID: j.HostID, Period: j.Period})
 }
 
 // nts - no need app.Scheduler.Start() in setup-app.go, since it's called in ToggleMonitoring in handlers.go
 func (repo *DBRepo) StartMonitoring() {
-	if app.PreferenceMap["monitoring_live"] == "1" {
+	if repo.monitoring() {
 		log.Println("**********starting monitoring***********")
 		// if set to zero, we don't want to monitor.
 
@@ -46,26 +60,18 @@ func (repo *DBRepo) StartMonitoring() {
 		for _, x := range servicesToMonitor {
 			log.Println("*** Services to monitor on", x.HostName, "is", x.Service.ServiceName)
 
-			// get the schedule unit and number
-			var schedule string // the frequency of a job .
-			if x.ScheduleUnit == "d" {
-				schedule = fmt.Sprintf("@every %d%s", x.ScheduleNumber*24, "h") // change to 24 hours
-			} else {
-				schedule = fmt.Sprintf("@every %d%s", x.ScheduleNumber, x.ScheduleUnit)
-			}
+			// the interval or cron expression the service is checked on
+			schedule := schedules.Spec(x)
 			// create a job of type job
-			var j job
-			j.HostServiceID = x.ID
-			// save the id of the job so we can start/stop it.
-			// schedule ID is of type cron.EntryID
-			scheduleID, err := app.Scheduler.AddJob(schedule, j)
+			j := newJob(x)
+			// the registry keeps the id of the job so we can start/stop it. A host service
+			// already scheduled is left as it is.
+			_, err = app.Monitors.Add(x.ID, schedule, j)
 			if err != nil {
 				log.Println(err)
+				continue
 			}
 
-			app.MonitorMap[x.ID] = scheduleID
-			// log.Printf("%s", x.HostName)
-
 			// for each of these task, to broadcast over websockets the fact that the service is scheduled.
 			// i.e. pending
 			payload := make(map[string]string)
@@ -74,8 +80,8 @@ func (repo *DBRepo) StartMonitoring() {
 			// nts - non null values. Only concerned about year here.
 			yearOne := time.Date(0001, 11, 17, 20, 34, 58, 651387, time.UTC)
 			// nts - check if the next entry is after year one (?)
-			if app.Scheduler.Entry(app.MonitorMap[x.ID]).Next.After(yearOne) {
-				payload["next_run"] = app.Scheduler.Entry(app.MonitorMap[x.ID]).Next.Format("2006-01-02 3:04:05 PM")
+			if next := app.Monitors.Next(x.ID); next.After(yearOne) {
+				payload["next_run"] = next.Format("2006-01-02 3:04:05 PM")
 			} else {
 				// "default" - if year one (0001-01-01 default value)
 				payload["next_run"] = "Pending..."
@@ -87,7 +93,7 @@ func (repo *DBRepo) StartMonitoring() {
 			} else {
 				payload["last_run"] = "Pending..."
 			}
-			payload["schedule"] = fmt.Sprintf("@every %d%s", x.ScheduleNumber, x.ScheduleUnit)
+			payload["schedule"] = schedules.Describe(x)
 
 			// first to send is next-run-event (next iteration)
 			err = app.WsClient.Trigger("public-channel", "next-run-event", payload)
@@ -102,9 +108,9 @@ func (repo *DBRepo) StartMonitoring() {
 			}
 
 		}
-		// nts - check to see which services being monitored.
-		// for k, v := range app.MonitorMap {
-		// 	log.Printf("serviceID is %d and cron id is %v", k, v)
-		// }
+		// unacknowledged incidents are escalated by a job of their own
+		repo.scheduleEscalations()
+		// and old check results are pruned by another
+		repo.schedulePruning()
 	}
 }
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/executor"
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// confirmStatus decides the status a host service should have after a check returned
+// status. A change only takes effect once the service's fail after / recover after
+// number of consecutive checks agree, and never while the service is flapping; until
+// then the current status is kept and the message says why.
+func (repo *DBRepo) confirmStatus(h models.Host, hs models.HostService, status, msg string) (string, string) {
+	before := hs
+
+	flapping := repo.isFlapping(hs)
+	if flapping && hs.Flapping == 0 {
+		hs.Flapping = 1
+		repo.recordEvent(h, hs, "flapping", fmt.Sprintf("%s on %s is flapping; status changes are held back", hs.Service.ServiceName, h.HostName))
+	} else if !flapping && hs.Flapping == 1 {
+		hs.Flapping = 0
+		repo.recordEvent(h, hs, "flapping stopped", fmt.Sprintf("%s on %s has stopped flapping", hs.Service.ServiceName, h.HostName))
+	}
+
+	// first result, or no change: nothing to confirm
+	if status == hs.Status || hs.Status == "pending" {
+		hs.ConfirmStatus, hs.ConfirmCount = "", 0
+		repo.saveCheckState(before, hs)
+		return status, msg
+	}
+
+	if hs.ConfirmStatus == status {
+		hs.ConfirmCount++
+	} else {
+		hs.ConfirmStatus, hs.ConfirmCount = status, 1
+	}
+
+	if hs.Flapping == 1 {
+		repo.saveCheckState(before, hs)
+		return hs.Status, fmt.Sprintf("%s (flapping, %s not applied)", msg, status)
+	}
+
+	required := hs.Config.FailAfter
+	if status == "healthy" {
+		required = hs.Config.RecoverAfter
+	}
+	if hs.ConfirmCount >= required {
+		hs.ConfirmStatus, hs.ConfirmCount = "", 0
+		repo.saveCheckState(before, hs)
+		return status, msg
+	}
+
+	repo.saveCheckState(before, hs)
+	repo.scheduleRecheck(hs)
+	return hs.Status, fmt.Sprintf("%s (unconfirmed %s, %d of %d checks)", msg, status, hs.ConfirmCount, required)
+}
+
+// isFlapping reports whether a host service's check results changed status at least
+// flap_threshold times in the last flap_window_minutes. A threshold of 0 turns it off.
+func (repo *DBRepo) isFlapping(hs models.HostService) bool {
+	threshold, _ := strconv.Atoi(repo.App.Preferences.Get("flap_threshold"))
+	minutes, _ := strconv.Atoi(repo.App.Preferences.Get("flap_window_minutes"))
+	if threshold <= 0 || minutes <= 0 {
+		return false
+	}
+
+	to := time.Now().Add(time.Second)
+	transitions, err := repo.DB.CountStatusChanges(hs.ID, to.Add(-time.Duration(minutes)*time.Minute), to)
+	if err != nil {
+		log.Println(err)
+		return hs.Flapping == 1
+	}
+	return transitions >= threshold
+}
+
+// saveCheckState saves the confirmation and flapping state if it has changed
+func (repo *DBRepo) saveCheckState(before, hs models.HostService) {
+	if before.ConfirmStatus == hs.ConfirmStatus && before.ConfirmCount == hs.ConfirmCount && before.Flapping == hs.Flapping {
+		return
+	}
+
+	err := repo.DB.UpdateHostServiceCheckState(hs)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// pendingRechecks holds the ids of the host services with a re-check waiting, so that a
+// service whose status keeps changing never has more than one at a time
+var (
+	recheckMu       sync.Mutex
+	pendingRechecks = make(map[int]bool)
+)
+
+// scheduleRecheck runs the check again after the host service's re-check interval,
+// so an unconfirmed change is settled sooner than the regular schedule would. Nothing
+// happens if a re-check is already waiting.
+func (repo *DBRepo) scheduleRecheck(hs models.HostService) {
+	if hs.Config.RecheckSeconds <= 0 || !repo.monitoring() {
+		return
+	}
+
+	recheckMu.Lock()
+	defer recheckMu.Unlock()
+	if pendingRechecks[hs.ID] {
+		return
+	}
+	pendingRechecks[hs.ID] = true
+
+	recheck := time.Duration(hs.Config.RecheckSeconds) * time.Second
+	time.AfterFunc(recheck, func() {
+		recheckMu.Lock()
+		delete(pendingRechecks, hs.ID)
+		recheckMu.Unlock()
+
+		repo.App.Checks.Submit(executor.Task{HostServiceID: hs.ID, HostID: hs.HostID, Period: recheck})
+	})
+}
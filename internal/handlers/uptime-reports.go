@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/reports"
+)
+
+// reportRanges are the preset windows offered on the reports page
+var reportRanges = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// uptimeErrorMessage is shown in place of availability figures that could not be worked out
+const uptimeErrorMessage = "Availability could not be worked out just now. Please try again later."
+
+// uptimeRow is one line of an uptime report
+type uptimeRow struct {
+	HostID        int
+	HostName      string
+	HostServiceID int
+	ServiceName   string
+	Summary       reports.Summary
+}
+
+// uptimeReport holds availability per host service, per host and overall
+type uptimeReport struct {
+	Range    string
+	From     time.Time
+	To       time.Time
+	Services []uptimeRow
+	Hosts    []uptimeRow
+	Overall  reports.Summary
+}
+
+// UptimeReport displays the uptime report page
+func (repo *DBRepo) UptimeReport(w http.ResponseWriter, r *http.Request) {
+	rangeName, from, to, err := reportWindow(r)
+	if err != nil {
+		repo.App.Session.Put(r.Context(), "error", err.Error())
+		http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("uptimeError", "")
+
+	report, err := repo.buildUptimeReport(from, to)
+	if err != nil {
+		log.Println(err)
+		vars.Set("uptimeError", uptimeErrorMessage)
+	}
+	report.Range = rangeName
+
+	vars.Set("report", report)
+	vars.Set("query", r.URL.RawQuery)
+
+	err = helpers.RenderPage(w, r, "reports", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// UptimeReportCSV sends the uptime report as a CSV download
+func (repo *DBRepo) UptimeReportCSV(w http.ResponseWriter, r *http.Request) {
+	_, from, to, err := reportWindow(r)
+	if err != nil {
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	report, err := repo.buildUptimeReport(from, to)
+	if err != nil {
+		ServerError(w, r, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("uptime-%s-%s.csv", from.Format("20060102"), to.Format("20060102"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+	out := csv.NewWriter(w)
+	_ = out.Write([]string{"host", "service", "from", "to", "uptime_percent", "downtime_seconds",
+		"incidents", "mttr_seconds", "observed_seconds"})
+
+	writeRow := func(host, service string, s reports.Summary) {
+		_ = out.Write([]string{
+			host,
+			service,
+			from.Format(time.RFC3339),
+			to.Format(time.RFC3339),
+			strconv.FormatFloat(s.UptimePercent(), 'f', 3, 64),
+			strconv.Itoa(int(s.Downtime.Seconds())),
+			strconv.Itoa(s.Incidents),
+			strconv.Itoa(int(s.MTTR().Seconds())),
+			strconv.Itoa(int(s.Observed.Seconds())),
+		})
+	}
+
+	for _, x := range report.Services {
+		writeRow(x.HostName, x.ServiceName, x.Summary)
+	}
+	for _, x := range report.Hosts {
+		writeRow(x.HostName, "all services", x.Summary)
+	}
+	writeRow("all hosts", "all services", report.Overall)
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Println(err)
+	}
+}
+
+// buildUptimeReport works out availability for every host service between from and to
+func (repo *DBRepo) buildUptimeReport(from, to time.Time) (uptimeReport, error) {
+	report := uptimeReport{From: from, To: to}
+
+	hosts, err := repo.DB.AllHosts()
+	if err != nil {
+		return report, err
+	}
+
+	totals, err := repo.DB.GetUptimeTotals(from, to)
+	if err != nil {
+		return report, err
+	}
+	summaries := reports.ByHostService(totals, from, to)
+
+	var all []reports.Summary
+	for _, h := range hosts {
+		var hostSummaries []reports.Summary
+		for _, hs := range h.HostServices {
+			summary, ok := summaries[hs.ID]
+			if !ok {
+				if hs.Active != 1 {
+					continue
+				}
+				summary = reports.Summary{From: from, To: to}
+			}
+
+			hostSummaries = append(hostSummaries, summary)
+			report.Services = append(report.Services, uptimeRow{
+				HostID:        h.ID,
+				HostName:      h.HostName,
+				HostServiceID: hs.ID,
+				ServiceName:   hs.Service.ServiceName,
+				Summary:       summary,
+			})
+		}
+
+		if len(hostSummaries) == 0 {
+			continue
+		}
+		hostSummary := reports.Combine(from, to, hostSummaries...)
+		all = append(all, hostSummary)
+		report.Hosts = append(report.Hosts, uptimeRow{
+			HostID:   h.ID,
+			HostName: h.HostName,
+			Summary:  hostSummary,
+		})
+	}
+
+	report.Overall = reports.Combine(from, to, all...)
+	return report, nil
+}
+
+// hostUptimeByRange works out the availability of each service on a host for each
+// preset range, ending now
+func (repo *DBRepo) hostUptimeByRange(h models.Host) (map[int]map[string]reports.Summary, error) {
+	now := time.Now()
+	uptime := make(map[int]map[string]reports.Summary)
+	for _, hs := range h.HostServices {
+		uptime[hs.ID] = make(map[string]reports.Summary)
+	}
+
+	for name, d := range reportRanges {
+		from := now.Add(-d)
+		totals, err := repo.DB.GetUptimeTotalsForHost(h.ID, from, now)
+		if err != nil {
+			return nil, err
+		}
+		summaries := reports.ByHostService(totals, from, now)
+		for _, hs := range h.HostServices {
+			summary, ok := summaries[hs.ID]
+			if !ok {
+				summary = reports.Summary{From: from, To: now}
+			}
+			uptime[hs.ID][name] = summary
+		}
+	}
+	return uptime, nil
+}
+
+// overallUptimeByRange works out the availability of all host services together for
+// each preset range, ending now
+func (repo *DBRepo) overallUptimeByRange() (map[string]reports.Summary, error) {
+	now := time.Now()
+	uptime := make(map[string]reports.Summary)
+
+	for name, d := range reportRanges {
+		from := now.Add(-d)
+		totals, err := repo.DB.GetUptimeTotals(from, now)
+		if err != nil {
+			return nil, err
+		}
+		var all []reports.Summary
+		for _, t := range totals {
+			all = append(all, reports.FromTotals(t, from, now))
+		}
+		uptime[name] = reports.Combine(from, now, all...)
+	}
+	return uptime, nil
+}
+
+// reportWindow reads the report window from the request: either a preset range
+// (24h, 7d, 30d) or range=custom with from and to dates (YYYY-MM-DD, inclusive)
+func reportWindow(r *http.Request) (string, time.Time, time.Time, error) {
+	rangeName := r.URL.Query().Get("range")
+	if rangeName == "" {
+		rangeName = "24h"
+	}
+
+	if d, ok := reportRanges[rangeName]; ok {
+		to := time.Now()
+		return rangeName, to.Add(-d), to, nil
+	}
+
+	if rangeName != "custom" {
+		return rangeName, time.Time{}, time.Time{}, errors.New("unknown report range")
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("from"), time.Local)
+	if err != nil {
+		return rangeName, time.Time{}, time.Time{}, errors.New("invalid from date")
+	}
+	to, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("to"), time.Local)
+	if err != nil {
+		return rangeName, time.Time{}, time.Time{}, errors.New("invalid to date")
+	}
+	to = to.AddDate(0, 0, 1)
+
+	if !from.Before(to) {
+		return rangeName, time.Time{}, time.Time{}, errors.New("from date must be before to date")
+	}
+	return rangeName, from, to, nil
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+	"github.com/brianmaksy/go-watch/internal/httpheaders"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/notifiers"
+	"github.com/go-chi/chi/v5"
+)
+
+// webhookDeliveryLogSize is how many deliveries the webhooks page shows
+const webhookDeliveryLogSize = 50
+
+// AllWebhooks lists webhooks and their most recent deliveries
+func (repo *DBRepo) AllWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := repo.DB.AllWebhooks()
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := repo.DB.GetRecentWebhookDeliveries(webhookDeliveryLogSize)
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("webhooks", webhooks)
+	vars.Set("deliveries", deliveries)
+
+	err = helpers.RenderPage(w, r, "webhooks", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// OneWebhook displays the add/edit webhook page
+func (repo *DBRepo) OneWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	wh := models.Webhook{Active: 1}
+	if id > 0 {
+		wh, err = repo.DB.GetWebhookByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("webhook", wh)
+	vars.Set("defaultTemplate", notifiers.DefaultWebhookTemplate)
+
+	err = helpers.RenderPage(w, r, "webhook", vars, nil)
+	if err != nil {
+		printTemplateError(w, err)
+	}
+}
+
+// PostOneWebhook adds/edits a webhook
+func (repo *DBRepo) PostOneWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(err)
+	}
+
+	var wh models.Webhook
+	if id > 0 {
+		wh, err = repo.DB.GetWebhookByID(id)
+		if err != nil {
+			ClientError(w, r, http.StatusBadRequest)
+			return
+		}
+	}
+
+	wh.Name = r.Form.Get("name")
+	wh.URL = r.Form.Get("url")
+	wh.BodyTemplate = r.Form.Get("body_template")
+	wh.Headers = r.Form.Get("headers")
+	wh.Active, _ = strconv.Atoi(r.Form.Get("active"))
+	if r.Form.Get("clear_secret") == "1" {
+		wh.Secret = ""
+	} else if r.Form.Get("secret") != "" {
+		wh.Secret = r.Form.Get("secret")
+	}
+
+	if msg := validateWebhook(repo.App.Preferences.Map(), wh); msg != "" {
+		repo.App.Session.Put(r.Context(), "error", msg)
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+		return
+	}
+
+	if id > 0 {
+		err = repo.DB.UpdateWebhook(wh)
+	} else {
+		_, err = repo.DB.InsertWebhook(wh)
+	}
+	if err != nil {
+		log.Println(err)
+		ClientError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	repo.App.Session.Put(r.Context(), "flash", "Changes saved")
+	http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func (repo *DBRepo) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	_ = repo.DB.DeleteWebhook(id)
+	repo.App.Session.Put(r.Context(), "flash", "Webhook deleted")
+	http.Redirect(w, r, "/admin/webhooks", http.StatusSeeOther)
+}
+
+// TestWebhook sends a sample notification to a saved webhook, once and without retries,
+// and returns JSON
+func (repo *DBRepo) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	var resp jsonResp
+	resp.OK = true
+
+	id, _ := strconv.Atoi(r.Form.Get("id"))
+	wh, err := repo.DB.GetWebhookByID(id)
+	if err != nil {
+		resp.OK = false
+		resp.Message = "Webhook not found"
+	} else {
+		wn := notifiers.NewWebhookNotifier(repo.DB)
+		d, err := wn.Deliver(wh, notifiers.SampleNotification(repo.App.Preferences.Map()), 1)
+		if err != nil {
+			resp.OK = false
+			resp.Message = err.Error()
+		} else {
+			resp.Message = "Delivered, response " + strconv.Itoa(d.ResponseCode)
+		}
+	}
+
+	out, _ := json.MarshalIndent(resp, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// validateWebhook returns a message describing the first problem with a webhook, or an
+// empty string if it is fine to save
+func validateWebhook(pm map[string]string, wh models.Webhook) string {
+	if wh.Name == "" {
+		return "Please enter a name"
+	}
+
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "Please enter an http or https url"
+	}
+
+	if _, err := httpheaders.Parse(wh.Headers); err != nil {
+		return err.Error()
+	}
+
+	if _, err := notifiers.RenderWebhookBody(wh.BodyTemplate, notifiers.SampleNotification(pm)); err != nil {
+		return "Invalid body template: " + err.Error()
+	}
+	return ""
+}
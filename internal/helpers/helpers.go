@@ -77,7 +77,11 @@ var views = jet.NewSet(
 func DefaultData(td templates.TemplateData, r *http.Request, w http.ResponseWriter) templates.TemplateData {
 	td.CSRFToken = nosurf.Token(r)
 	td.IsAuthenticated = IsAuthenticated(r)
-	td.PreferenceMap = app.PreferenceMap
+	td.PreferenceMap = app.Preferences.Map()
+	if app.Leader != nil {
+		td.Instance = app.Leader.Holder()
+		td.Leader = app.Leader.Lease().Holder
+	}
 	// if logged in, store user id in template data
 	if td.IsAuthenticated {
 		u := app.Session.Get(r.Context(), "user").(models.User)
@@ -6,8 +6,8 @@ import "github.com/brianmaksy/go-watch/internal/channeldata"
 func SendEmail(mailMessage channeldata.MailData) {
 	// if no sender specified, use defaults
 	if mailMessage.FromAddress == "" {
-		mailMessage.FromAddress = app.PreferenceMap["smtp_from_email"]
-		mailMessage.FromName = app.PreferenceMap["smtp_from_name"]
+		mailMessage.FromAddress = app.Preferences.Get("smtp_from_email")
+		mailMessage.FromName = app.Preferences.Get("smtp_from_name")
 	}
 
 	job := channeldata.MailJob{MailMessage: mailMessage}
@@ -1,6 +1,13 @@
 package helpers
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/checkers"
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/brianmaksy/go-watch/internal/schedules"
+)
 
 func addTemplateFunctions() {
 	views.AddGlobal("humanDate", func(t time.Time) string {
@@ -14,6 +21,26 @@ func addTemplateFunctions() {
 	views.AddGlobal("dateAfterYearOne", func(t time.Time) bool {
 		return DateAfterY1(t)
 	})
+
+	views.AddGlobal("dnsRecordTypes", func() []string {
+		return checkers.DNSRecordTypes
+	})
+
+	views.AddGlobal("httpMethods", func() []string {
+		return checkers.HTTPMethods
+	})
+
+	views.AddGlobal("pausedReason", func(h models.Host, hs models.HostService) string {
+		return schedules.Paused(h, hs)
+	})
+
+	views.AddGlobal("formatPercent", func(f float64) string {
+		return fmt.Sprintf("%.3f%%", f)
+	})
+
+	views.AddGlobal("formatDuration", func(d time.Duration) string {
+		return FormatDuration(d)
+	})
 }
 
 // HumanDate formats a time in YYYY-MM-DD format
@@ -34,3 +61,8 @@ func DateAfterY1(t time.Time) bool {
 	yearOne := time.Date(0001, 11, 17, 20, 34, 58, 651387237, time.UTC)
 	return t.After(yearOne)
 }
+
+// FormatDuration formats a duration to the second, e.g. 1h2m3s, or 0s for none
+func FormatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,25 @@
+package httpheaders
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Parse parses headers given one per line in "Name: value" form, as they are entered
+// for checks and webhooks
+func Parse(s string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid header '%s'", line)
+		}
+		headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return headers, nil
+}
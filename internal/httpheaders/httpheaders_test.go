@@ -0,0 +1,22 @@
+package httpheaders
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	h, err := Parse("Authorization: Bearer abc:def\n\n  x-custom :  one \nX-Custom: two\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get("Authorization"); got != "Bearer abc:def" {
+		t.Errorf("Authorization is %q", got)
+	}
+	if got := h.Values("X-Custom"); len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Errorf("X-Custom is %v", got)
+	}
+
+	for _, s := range []string{"no colon", ": no name"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) did not fail", s)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package leader
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// Leases is where a lease is kept, so that every instance sees the same one
+type Leases interface {
+	AcquireLease(name, holder string, ttl time.Duration) (models.Lease, error)
+	ReleaseLease(name, holder string) error
+}
+
+// Elector decides which of several instances is the leader, by holding a lease that it
+// renews three times per ttl. A standby takes the lease, and with it the lead, once the
+// leader has let it expire.
+type Elector struct {
+	leases Leases
+	name   string
+	holder string
+	ttl    time.Duration
+
+	// OnElected is called when this instance becomes the leader, OnRenewed whenever it
+	// renews its lease afterwards, and OnDemoted when it stops being the leader
+	OnElected func()
+	OnRenewed func()
+	OnDemoted func()
+
+	stop chan struct{}
+	done chan struct{}
+
+	mu         sync.Mutex
+	leader     bool
+	lease      models.Lease // as last seen
+	validUntil time.Time    // when the lease last renewed expires at the earliest
+}
+
+// New returns an elector for the lease name, held as holder for ttl at a time
+func New(leases Leases, name, holder string, ttl time.Duration) *Elector {
+	return &Elector{
+		leases: leases,
+		name:   name,
+		holder: holder,
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Run takes or renews the lease straight away, then every third of its ttl until Stop
+// is called
+func (e *Elector) Run() {
+	defer close(e.done)
+
+	interval := e.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	e.renew(interval)
+	for {
+		select {
+		case <-ticker.C:
+			e.renew(interval)
+		case <-e.stop:
+			e.resign()
+			return
+		}
+	}
+}
+
+// renew tries to take or renew the lease. A leader that cannot renew it steps down
+// before the next try would come too late, so two leaders never run at once.
+func (e *Elector) renew(interval time.Duration) {
+	started := time.Now()
+	l, err := e.leases.AcquireLease(e.name, e.holder, e.ttl)
+
+	e.mu.Lock()
+	was := e.leader
+	if err != nil {
+		log.Println("Could not renew lease", e.name+":", err)
+		if e.leader && time.Now().Add(interval).After(e.validUntil) {
+			e.leader = false
+		}
+	} else {
+		e.lease = l
+		e.leader = l.Holder == e.holder
+		if e.leader {
+			e.validUntil = started.Add(e.ttl)
+		}
+	}
+	is := e.leader
+	e.mu.Unlock()
+
+	switch {
+	case is && !was:
+		log.Println("This instance,", e.holder, "is now the leader")
+		call(e.OnElected)
+	case is:
+		call(e.OnRenewed)
+	case was:
+		log.Println("This instance,", e.holder, "is no longer the leader")
+		call(e.OnDemoted)
+	}
+}
+
+// Stop stops Run, and releases the lease if this instance holds it, so a standby can
+// take over without waiting for it to expire
+func (e *Elector) Stop() {
+	close(e.stop)
+	<-e.done
+}
+
+// resign gives up the lead and releases the lease
+func (e *Elector) resign() {
+	e.mu.Lock()
+	was := e.leader
+	e.leader = false
+	e.mu.Unlock()
+
+	if !was {
+		return
+	}
+	call(e.OnDemoted)
+	err := e.leases.ReleaseLease(e.name, e.holder)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// IsLeader reports whether this instance is the leader
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Lease returns the lease as last seen, which names the leader
+func (e *Elector) Lease() models.Lease {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lease
+}
+
+// Holder returns the name of this instance
+func (e *Elector) Holder() string {
+	return e.holder
+}
+
+// call calls f, if it is set
+func call(f func()) {
+	if f != nil {
+		f()
+	}
+}
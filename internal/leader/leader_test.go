@@ -0,0 +1,202 @@
+package leader
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// fakeLeases keeps one lease in memory the way the leases table does: it goes to
+// whoever asks once it has expired, and is renewed for its holder
+type fakeLeases struct {
+	mu   sync.Mutex
+	l    models.Lease
+	down map[string]bool // holders that cannot reach the database
+}
+
+func newFakeLeases() *fakeLeases {
+	return &fakeLeases{down: make(map[string]bool)}
+}
+
+func (f *fakeLeases) AcquireLease(name, holder string, ttl time.Duration) (models.Lease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down[holder] {
+		return models.Lease{}, errors.New("database unavailable")
+	}
+	now := time.Now()
+	if f.l.Holder == holder || !f.l.ExpiresAt.After(now) {
+		f.l = models.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}
+	}
+	return f.l, nil
+}
+
+func (f *fakeLeases) ReleaseLease(name, holder string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.l.Holder == holder {
+		f.l.ExpiresAt = time.Now()
+	}
+	return nil
+}
+
+// setDown makes the database unavailable to holder, or available again
+func (f *fakeLeases) setDown(holder string, down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down[holder] = down
+}
+
+// lease returns the lease as stored
+func (f *fakeLeases) lease() models.Lease {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.l
+}
+
+// leaders counts the electors that are leading at once, through their callbacks
+type leaders struct {
+	now, max int32
+}
+
+func (c *leaders) elected() {
+	n := atomic.AddInt32(&c.now, 1)
+	for {
+		m := atomic.LoadInt32(&c.max)
+		if n <= m || atomic.CompareAndSwapInt32(&c.max, m, n) {
+			return
+		}
+	}
+}
+
+func (c *leaders) demoted() {
+	atomic.AddInt32(&c.now, -1)
+}
+
+const testTTL = 150 * time.Millisecond
+
+// start runs an elector for holder, counting its time as leader in c
+func start(t *testing.T, f *fakeLeases, holder string, c *leaders) *Elector {
+	t.Helper()
+
+	e := New(f, "scheduler", holder, testTTL)
+	e.OnElected = c.elected
+	e.OnDemoted = c.demoted
+	go e.Run()
+	return e
+}
+
+// waitFor waits up to a few ttls for cond to hold
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * testTTL)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestElectorAcquiresAndRenews(t *testing.T) {
+	f := newFakeLeases()
+	var c leaders
+
+	e := New(f, "scheduler", "a", testTTL)
+	e.OnElected = c.elected
+	var renewed int32
+	e.OnRenewed = func() { atomic.AddInt32(&renewed, 1) }
+	go e.Run()
+	defer e.Stop()
+
+	waitFor(t, "a leads", e.IsLeader)
+	first := f.lease().ExpiresAt
+
+	waitFor(t, "a renews twice", func() bool { return atomic.LoadInt32(&renewed) >= 2 })
+	if !e.IsLeader() || e.Lease().Holder != "a" {
+		t.Errorf("a lost the lead while renewing, lease %+v", e.Lease())
+	}
+	if !f.lease().ExpiresAt.After(first) {
+		t.Error("renewing did not extend the lease")
+	}
+	if n := atomic.LoadInt32(&c.max); n != 1 {
+		t.Errorf("elected %d times, want once", n)
+	}
+}
+
+func TestElectorStepsDownBeforeLeaseExpires(t *testing.T) {
+	f := newFakeLeases()
+	var c leaders
+
+	e := New(f, "scheduler", "a", testTTL)
+	e.OnElected = c.elected
+	var demotedAt atomic.Value
+	e.OnDemoted = func() { demotedAt.Store(time.Now()) }
+	go e.Run()
+	defer e.Stop()
+
+	waitFor(t, "a leads", e.IsLeader)
+	f.setDown("a", true)
+
+	waitFor(t, "a steps down", func() bool { return !e.IsLeader() })
+	at, _ := demotedAt.Load().(time.Time)
+	if at.IsZero() {
+		t.Fatal("OnDemoted was not called")
+	}
+	if expires := f.lease().ExpiresAt; at.After(expires) {
+		t.Errorf("stepped down at %s, after the lease expired at %s", at.Format(time.StampMilli), expires.Format(time.StampMilli))
+	}
+}
+
+func TestElectorsCompete(t *testing.T) {
+	f := newFakeLeases()
+	var c leaders
+
+	a := start(t, f, "a", &c)
+	waitFor(t, "a leads", a.IsLeader)
+	b := start(t, f, "b", &c)
+
+	// b stands by while a renews
+	time.Sleep(2 * testTTL)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("a leads %v, b leads %v, want a only", a.IsLeader(), b.IsLeader())
+	}
+	if b.Lease().Holder != "a" {
+		t.Errorf("b sees %q as the leader, want a", b.Lease().Holder)
+	}
+
+	// a loses the database, and b takes over once a's lease has expired
+	f.setDown("a", true)
+	waitFor(t, "b takes over", b.IsLeader)
+	if a.IsLeader() {
+		t.Error("a still leads after b took over")
+	}
+
+	// a comes back, and stands by
+	f.setDown("a", false)
+	time.Sleep(testTTL)
+	if a.IsLeader() || !b.IsLeader() {
+		t.Errorf("a leads %v, b leads %v, want b only", a.IsLeader(), b.IsLeader())
+	}
+
+	// b stops and releases the lease, so a takes over on its next try, well before
+	// b's lease would have expired
+	stopped := time.Now()
+	b.Stop()
+	waitFor(t, "a takes over", a.IsLeader)
+	if took := time.Since(stopped); took > testTTL {
+		t.Errorf("a took %s to take over a released lease", took)
+	}
+	a.Stop()
+
+	if n := atomic.LoadInt32(&c.max); n != 1 {
+		t.Errorf("%d electors led at once", n)
+	}
+}
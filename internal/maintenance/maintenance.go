@@ -0,0 +1,172 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// Scopes a maintenance window can apply to
+const (
+	ScopeAll         = "all"
+	ScopeHost        = "host"
+	ScopeHostService = "host_service"
+)
+
+// Period is one occurrence of a maintenance window
+type Period struct {
+	Window models.MaintenanceWindow
+	Start  time.Time
+	End    time.Time
+}
+
+// Recurring reports whether a window repeats on a cron expression
+func Recurring(mw models.MaintenanceWindow) bool {
+	return mw.CronExpression != ""
+}
+
+// Validate returns an error describing the first problem with a window
+func Validate(mw models.MaintenanceWindow) error {
+	if mw.Name == "" {
+		return errors.New("please enter a name")
+	}
+
+	switch mw.Scope {
+	case ScopeAll:
+	case ScopeHost:
+		if mw.HostID == 0 {
+			return errors.New("please choose a host")
+		}
+	case ScopeHostService:
+		if mw.HostServiceID == 0 {
+			return errors.New("please choose a host service")
+		}
+	default:
+		return fmt.Errorf("unknown scope '%s'", mw.Scope)
+	}
+
+	if Recurring(mw) {
+		if _, err := cron.ParseStandard(mw.CronExpression); err != nil {
+			return fmt.Errorf("invalid cron expression: %s", err)
+		}
+		if mw.DurationMinutes <= 0 {
+			return errors.New("a recurring window needs a duration")
+		}
+		return nil
+	}
+
+	if mw.StartsAt.IsZero() || mw.EndsAt.IsZero() {
+		return errors.New("a one-off window needs a start and an end")
+	}
+	if !mw.EndsAt.After(mw.StartsAt) {
+		return errors.New("the window must end after it starts")
+	}
+	return nil
+}
+
+// Covers reports whether a window applies to a host service
+func Covers(mw models.MaintenanceWindow, hostID, hostServiceID int) bool {
+	switch mw.Scope {
+	case ScopeAll:
+		return true
+	case ScopeHost:
+		return mw.HostID == hostID
+	case ScopeHostService:
+		return mw.HostServiceID == hostServiceID
+	}
+	return false
+}
+
+// CurrentPeriod returns the occurrence of a window that t falls in, if any
+func CurrentPeriod(mw models.MaintenanceWindow, t time.Time) (Period, bool) {
+	if mw.Active != 1 {
+		return Period{}, false
+	}
+
+	if !Recurring(mw) {
+		if !t.Before(mw.StartsAt) && t.Before(mw.EndsAt) {
+			return Period{Window: mw, Start: mw.StartsAt, End: mw.EndsAt}, true
+		}
+		return Period{}, false
+	}
+
+	sched, err := cron.ParseStandard(mw.CronExpression)
+	if err != nil {
+		return Period{}, false
+	}
+	duration := time.Duration(mw.DurationMinutes) * time.Minute
+
+	// a period contains t if it starts at or before t, and after t-duration, so the
+	// first start after t-duration is the only one to look at. Next is strictly after,
+	// which leaves out the period that ends exactly at t.
+	start := sched.Next(t.Add(-duration))
+	if start.IsZero() || start.After(t) {
+		return Period{}, false
+	}
+	end := start.Add(duration)
+	if !t.Before(end) {
+		return Period{}, false
+	}
+	return Period{Window: mw, Start: start, End: end}, true
+}
+
+// NextPeriod returns the first occurrence of a window that starts after t
+func NextPeriod(mw models.MaintenanceWindow, t time.Time) (Period, bool) {
+	if mw.Active != 1 {
+		return Period{}, false
+	}
+
+	if !Recurring(mw) {
+		if mw.StartsAt.After(t) {
+			return Period{Window: mw, Start: mw.StartsAt, End: mw.EndsAt}, true
+		}
+		return Period{}, false
+	}
+
+	sched, err := cron.ParseStandard(mw.CronExpression)
+	if err != nil {
+		return Period{}, false
+	}
+	start := sched.Next(t)
+	if start.IsZero() {
+		return Period{}, false
+	}
+	return Period{Window: mw, Start: start, End: start.Add(time.Duration(mw.DurationMinutes) * time.Minute)}, true
+}
+
+// InMaintenance returns the window a host service is in at t, if any
+func InMaintenance(windows []models.MaintenanceWindow, hostID, hostServiceID int, t time.Time) (Period, bool) {
+	for _, mw := range windows {
+		if !Covers(mw, hostID, hostServiceID) {
+			continue
+		}
+		if p, ok := CurrentPeriod(mw, t); ok {
+			return p, true
+		}
+	}
+	return Period{}, false
+}
+
+// ActiveAndUpcoming returns the periods of all windows that are on at t or start
+// before t+ahead, soonest first
+func ActiveAndUpcoming(windows []models.MaintenanceWindow, t time.Time, ahead time.Duration) []Period {
+	var periods []Period
+	for _, mw := range windows {
+		if p, ok := CurrentPeriod(mw, t); ok {
+			periods = append(periods, p)
+			continue
+		}
+		if p, ok := NextPeriod(mw, t); ok && p.Start.Before(t.Add(ahead)) {
+			periods = append(periods, p)
+		}
+	}
+
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+	return periods
+}
@@ -0,0 +1,176 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // the DST tests load Europe/London wherever they run
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+func TestCurrentPeriodOneOff(t *testing.T) {
+	start := time.Date(2026, 10, 20, 22, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	mw := models.MaintenanceWindow{Name: "upgrade", Scope: ScopeAll, StartsAt: start, EndsAt: end, Active: 1}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		in   bool
+	}{
+		{"just before the start", start.Add(-time.Nanosecond), false},
+		{"at the start", start, true},
+		{"during", start.Add(time.Hour), true},
+		{"just before the end", end.Add(-time.Nanosecond), true},
+		{"at the end", end, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := CurrentPeriod(mw, tt.t)
+			if ok != tt.in {
+				t.Fatalf("in maintenance = %v, want %v", ok, tt.in)
+			}
+			if ok && (!p.Start.Equal(start) || !p.End.Equal(end)) {
+				t.Errorf("period %s - %s, want %s - %s", p.Start, p.End, start, end)
+			}
+		})
+	}
+
+	mw.Active = 0
+	if _, ok := CurrentPeriod(mw, start); ok {
+		t.Error("inactive window is in maintenance")
+	}
+}
+
+func TestCurrentPeriodRecurring(t *testing.T) {
+	day := func(h, m, s int) time.Time { return time.Date(2026, 10, 20, h, m, s, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		cron    string
+		minutes int
+		t       time.Time
+		in      bool
+		start   time.Time
+	}{
+		{"before the start", "0 2 * * *", 60, day(1, 59, 59), false, time.Time{}},
+		{"at the start", "0 2 * * *", 60, day(2, 0, 0), true, day(2, 0, 0)},
+		{"just before the end", "0 2 * * *", 60, day(2, 59, 59).Add(999 * time.Millisecond), true, day(2, 0, 0)},
+		{"at the end", "0 2 * * *", 60, day(3, 0, 0), false, time.Time{}},
+		{"between occurrences", "0 2 * * *", 60, day(12, 0, 0), false, time.Time{}},
+		{"window running past midnight", "0 23 * * *", 120, day(0, 30, 0), true, day(0, 0, 0).Add(-time.Hour)},
+		{"end of one period is the start of the next", "*/30 * * * *", 30, day(10, 30, 0), true, day(10, 30, 0)},
+		{"overlapping periods give the earliest", "*/30 * * * *", 45, day(10, 40, 0), true, day(10, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := models.MaintenanceWindow{Name: "nightly", Scope: ScopeAll, CronExpression: tt.cron, DurationMinutes: tt.minutes, Active: 1}
+			p, ok := CurrentPeriod(mw, tt.t.In(time.UTC))
+			if ok != tt.in {
+				t.Fatalf("in maintenance = %v, want %v", ok, tt.in)
+			}
+			if ok {
+				if !p.Start.Equal(tt.start) || !p.End.Equal(tt.start.Add(time.Duration(tt.minutes)*time.Minute)) {
+					t.Errorf("period %s - %s, want it to start at %s", p.Start, p.End, tt.start)
+				}
+			}
+		})
+	}
+}
+
+func TestNextPeriod(t *testing.T) {
+	start := time.Date(2026, 10, 20, 2, 0, 0, 0, time.UTC)
+
+	recurring := models.MaintenanceWindow{Name: "nightly", Scope: ScopeAll, CronExpression: "0 2 * * *", DurationMinutes: 60, Active: 1}
+	p, ok := NextPeriod(recurring, start)
+	if !ok || !p.Start.Equal(start.Add(24*time.Hour)) {
+		t.Errorf("next period at its own start is %s, want the day after", p.Start)
+	}
+	p, ok = NextPeriod(recurring, start.Add(-time.Nanosecond))
+	if !ok || !p.Start.Equal(start) {
+		t.Errorf("next period just before the start is %s, want %s", p.Start, start)
+	}
+
+	oneOff := models.MaintenanceWindow{Name: "upgrade", Scope: ScopeAll, StartsAt: start, EndsAt: start.Add(time.Hour), Active: 1}
+	if _, ok := NextPeriod(oneOff, start); ok {
+		t.Error("a one-off window that has started has a next period")
+	}
+	if p, ok := NextPeriod(oneOff, start.Add(-time.Minute)); !ok || !p.Start.Equal(start) {
+		t.Errorf("next period of a one-off window is %s, want %s", p.Start, start)
+	}
+}
+
+func TestCurrentPeriodAcrossDST(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(y int, m time.Month, d, h, min int) time.Time { return time.Date(y, m, d, h, min, 0, 0, london) }
+	mw := models.MaintenanceWindow{Name: "nightly", Scope: ScopeAll, CronExpression: "0 0 * * *", Active: 1}
+
+	t.Run("clocks go forward", func(t *testing.T) {
+		// on 29 March 2026 01:00 GMT becomes 02:00 BST, so three hours from midnight
+		// end at 04:00 on the clock
+		mw.DurationMinutes = 180
+		if _, ok := CurrentPeriod(mw, at(2026, 3, 29, 3, 59)); !ok {
+			t.Error("not in maintenance at 03:59 BST")
+		}
+		p, ok := CurrentPeriod(mw, at(2026, 3, 29, 1, 30).Add(-time.Hour)) // 00:30 GMT
+		if !ok || !p.End.Equal(at(2026, 3, 29, 4, 0)) {
+			t.Errorf("period ends at %s, want 04:00 BST", p.End)
+		}
+		if _, ok := CurrentPeriod(mw, at(2026, 3, 29, 4, 0)); ok {
+			t.Error("still in maintenance at 04:00 BST")
+		}
+	})
+
+	t.Run("clocks go back", func(t *testing.T) {
+		// on 25 October 2026 02:00 BST becomes 01:00 GMT, so two hours from midnight end
+		// at 01:00 GMT, the second time the clock shows 01:00
+		mw.DurationMinutes = 120
+		midnight := at(2026, 10, 25, 0, 0)
+		firstOneThirty := midnight.Add(90 * time.Minute) // 01:30 BST
+		secondOne := midnight.Add(2 * time.Hour)         // 01:00 GMT
+
+		if _, ok := CurrentPeriod(mw, firstOneThirty); !ok {
+			t.Error("not in maintenance at 01:30 BST")
+		}
+		if _, ok := CurrentPeriod(mw, secondOne.Add(-time.Second)); !ok {
+			t.Error("not in maintenance just before 01:00 GMT")
+		}
+		if _, ok := CurrentPeriod(mw, secondOne); ok {
+			t.Error("still in maintenance at 01:00 GMT")
+		}
+		if p, ok := NextPeriod(mw, secondOne); !ok || !p.Start.Equal(at(2026, 10, 26, 0, 0)) {
+			t.Errorf("next period starts at %s, want midnight on the 26th", p.Start)
+		}
+	})
+}
+
+func TestInMaintenance(t *testing.T) {
+	now := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	on := func(scope string, hostID, hostServiceID int) models.MaintenanceWindow {
+		return models.MaintenanceWindow{Name: scope, Scope: scope, HostID: hostID, HostServiceID: hostServiceID,
+			StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Active: 1}
+	}
+
+	tests := []struct {
+		name    string
+		windows []models.MaintenanceWindow
+		in      bool
+	}{
+		{"everything", []models.MaintenanceWindow{on(ScopeAll, 0, 0)}, true},
+		{"the host", []models.MaintenanceWindow{on(ScopeHost, 1, 0)}, true},
+		{"another host", []models.MaintenanceWindow{on(ScopeHost, 2, 0)}, false},
+		{"the host service", []models.MaintenanceWindow{on(ScopeHostService, 0, 10)}, true},
+		{"another host service", []models.MaintenanceWindow{on(ScopeHostService, 0, 11)}, false},
+		{"none", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := InMaintenance(tt.windows, 1, 10, now); ok != tt.in {
+				t.Errorf("in maintenance = %v, want %v", ok, tt.in)
+			}
+		})
+	}
+}
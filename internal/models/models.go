@@ -26,6 +26,7 @@ type User struct {
 	UserActive  int
 	AccessLevel int
 	Email       string
+	Phone       string // number that receives text messages while on call
 	Password    []byte
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -56,12 +57,24 @@ type Host struct {
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	HostServices  []HostService
+
+	// PagerDutyRoutingKey overrides the global pagerduty_routing_key for this host's services
+	PagerDutyRoutingKey string
+
+	// EscalationPolicyID is the policy followed when an incident on this host is not
+	// acknowledged, or 0 for none
+	EscalationPolicyID int
+
+	// OnCallScheduleID is the schedule whose on-call user is notified about this host,
+	// or 0 to notify the addresses in settings
+	OnCallScheduleID int
 }
 
 // model for services
 type Services struct {
 	ID          int
 	ServiceName string
+	ServiceType string // name the service's checker is registered under
 	Active      int
 	Icon        string
 	CreatedAt   time.Time
@@ -84,6 +97,41 @@ type HostService struct {
 	UpdatedAt      time.Time
 	Service        Services
 	HostName       string // not part of database, but for convenient in GetServicesByStatus database function
+	Config         CheckConfig
+
+	ConfirmStatus string // status seen in the last checks but not yet confirmed
+	ConfirmCount  int    // consecutive checks that returned ConfirmStatus
+	Flapping      int    // 1 while the status changes too often to be trusted
+
+	// ScheduleCron is a five-field cron expression the service is checked on instead of
+	// every ScheduleNumber ScheduleUnit; ScheduleTimeZone is the zone it is read in,
+	// blank for server time
+	ScheduleCron     string
+	ScheduleTimeZone string
+}
+
+// CheckConfig holds the per host service settings used by checkers
+type CheckConfig struct {
+	Port          int
+	DNSResolver   string // host:port of the resolver to query, blank for the system resolver
+	DNSRecordType string
+	DNSExpected   string // comma separated values the answer must contain
+
+	HTTPPath        string
+	HTTPMethod      string
+	HTTPHeaders     string // one "Name: value" per line
+	HTTPBody        string
+	ExpectedStatus  string // accepted codes and ranges, e.g. "200-299, 301"
+	BodyMatch       string // substring or regular expression the body is matched against
+	BodyMatchRegex  int
+	BodyMatchNegate int // 1 if the body must not match
+
+	WarnLatency     int // milliseconds; slower healthy checks become warnings, 0 is off
+	CriticalLatency int // milliseconds; slower healthy checks become problems, 0 is off
+
+	FailAfter      int // consecutive failed checks before the status changes
+	RecoverAfter   int // consecutive healthy checks before a failed service recovers
+	RecheckSeconds int // re-check this soon while a change is unconfirmed, 0 waits for the schedule
 }
 
 // Schedule model
@@ -96,6 +144,7 @@ type Schedule struct {
 	LastRunFromHS time.Time
 	HostServiceID int
 	ScheduleText  string
+	Maintenance   string // name of the maintenance window the host service is in, if any
 }
 
 type Event struct {
@@ -106,6 +155,196 @@ type Event struct {
 	ServiceName   string
 	HostName      string
 	Message       string
+	Maintenance   int // 1 if the event happened during a maintenance window
+	IncidentID    int // the incident the event belongs to, or 0
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// CheckResult is the outcome of a single run of a host service check
+type CheckResult struct {
+	ID            int
+	HostServiceID int
+	HostID        int
+	Status        string
+	LatencyMS     int
+	Message       string
+	ErrorClass    string
+	Maintenance   int // 1 if the check ran during a maintenance window
+	IncidentID    int // the incident the result belongs to, or 0
+	CheckedAt     time.Time
+	CreatedAt     time.Time
+}
+
+// UptimeTotal adds up the check history and status events of a host service over a
+// window of time
+type UptimeTotal struct {
+	HostServiceID int
+	Observed      time.Duration // time held by check results, less maintenance
+	Maintenance   time.Duration // time held by check results taken during maintenance
+	Downtime      time.Duration // time held by problem results
+	Incidents     int           // times the service went into problem
+	Recovered     int           // incidents that ended within the window
+	TimeToRecover time.Duration // total length of recovered incidents
+}
+
+// Webhook is an outbound http endpoint that is told about status changes
+type Webhook struct {
+	ID           int
+	Name         string
+	URL          string
+	BodyTemplate string // go template producing the json body; empty means the default body
+	Headers      string // extra headers, one "Name: value" per line
+	Secret       string // key for the HMAC-SHA256 signature header; empty means unsigned
+	Active       int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// WebhookDelivery is the log of sending one notification to a webhook
+type WebhookDelivery struct {
+	ID            int
+	WebhookID     int
+	WebhookName   string
+	HostServiceID int
+	Event         string
+	Status        string
+	Attempts      int
+	ResponseCode  int
+	ResponseBody  string
+	Error         string
+	Payload       string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
+
+// MaintenanceWindow is a period in which status changes do not notify anyone and do
+// not count against uptime. It is either one-off (StartsAt to EndsAt) or recurring
+// (starting on CronExpression and lasting DurationMinutes).
+type MaintenanceWindow struct {
+	ID              int
+	Name            string
+	Scope           string // all, host or host_service
+	HostID          int
+	HostServiceID   int
+	StartsAt        time.Time
+	EndsAt          time.Time
+	CronExpression  string
+	DurationMinutes int
+	Active          int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// HostDependency says that a host, or one of its services, depends on a parent host or
+// parent host service. A HostServiceID of 0 means the whole host depends on the parent;
+// a ParentHostServiceID of 0 means any of the parent's active services. While a parent
+// is down, failures of the dependent services are recorded as unreachable.
+type HostDependency struct {
+	ID                  int
+	HostID              int
+	HostServiceID       int
+	ParentHostID        int
+	ParentHostServiceID int
+	HostName            string
+	ServiceName         string
+	ParentHostName      string
+	ParentServiceName   string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// Incident groups everything that happened to a host service from the status change
+// that put it in problem until it recovered. It is open, acknowledged or resolved.
+type Incident struct {
+	ID                 int
+	HostID             int
+	HostServiceID      int
+	Title              string
+	Status             string
+	OpenedAt           time.Time
+	AcknowledgedAt     time.Time
+	AcknowledgedBy     int
+	AcknowledgedByName string
+	ResolvedAt         time.Time
+	Resolution         string
+	EscalationStep     int // how many escalation steps have been notified
+	LastEscalatedAt    time.Time
+	HostName           string
+	ServiceName        string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// IncidentNote is a timestamped note a user added to an incident
+type IncidentNote struct {
+	ID         int
+	IncidentID int
+	UserID     int
+	UserName   string
+	Note       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// EscalationPolicy decides who else hears about an incident that nobody acknowledges.
+// Its steps are notified in order, each once its delay after the incident opened has
+// passed; after that the latest step is reminded every RepeatMinutes, if set.
+type EscalationPolicy struct {
+	ID            int
+	Name          string
+	RepeatMinutes int
+	Active        int
+	Steps         []EscalationStep
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// EscalationStep is one step of an escalation policy. UserIDs and Channels are comma
+// separated lists of user ids and notifier names.
+type EscalationStep struct {
+	ID                 int
+	EscalationPolicyID int
+	Position           int
+	DelayMinutes       int
+	UserIDs            string
+	Channels           string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// OnCallSchedule is a rotation of users who take turns being on call
+type OnCallSchedule struct {
+	ID            int
+	Name          string
+	TimeZone      string    // IANA time zone handoffs are worked out in, e.g. America/Halifax
+	UserIDs       string    // comma separated user ids, in rotation order
+	RotationStart time.Time // first handoff; later ones fall at the same local time of day
+	ShiftDays     int       // how many days each user is on call for
+	Overrides     []OnCallOverride
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// OnCallOverride puts a user on call in place of the rotation for a while. Overrides
+// are layered: where they overlap, the one added last wins.
+type OnCallOverride struct {
+	ID               int
+	OnCallScheduleID int
+	UserID           int
+	UserName         string
+	StartsAt         time.Time
+	EndsAt           time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// Lease is held by the one instance that runs a job when several run at once, until it
+// expires or is renewed
+type Lease struct {
+	Name      string
+	Holder    string // name of the instance holding the lease
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
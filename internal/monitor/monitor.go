@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Item is a host service scheduled for checks
+type Item struct {
+	HostServiceID int
+	Spec          string
+	EntryID       cron.EntryID
+	Entry         cron.Entry
+}
+
+// entry is what the registry keeps for a scheduled host service
+type entry struct {
+	spec string
+	id   cron.EntryID
+}
+
+// Registry owns the scheduler entries of the host services being monitored, by host
+// service id. It is the only place check jobs are added to or removed from the
+// scheduler, and is safe to use from handlers and the monitoring goroutine at once.
+type Registry struct {
+	mu        sync.Mutex
+	scheduler *cron.Cron
+	entries   map[int]entry
+}
+
+// NewRegistry returns an empty registry scheduling jobs on scheduler
+func NewRegistry(scheduler *cron.Cron) *Registry {
+	return &Registry{
+		scheduler: scheduler,
+		entries:   make(map[int]entry),
+	}
+}
+
+// Add schedules j for a host service on spec. A host service is only ever scheduled
+// once: if it already is on the same spec, nothing changes and its next run is kept;
+// if it is on another spec, it is rescheduled.
+func (r *Registry) Add(hostServiceID int, spec string, j cron.Job) (cron.EntryID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[hostServiceID]; ok && e.spec == spec {
+		return e.id, nil
+	}
+	return r.schedule(hostServiceID, spec, j)
+}
+
+// Reschedule schedules j for a host service on spec, replacing any entry it already
+// has, so its next run is worked out afresh
+func (r *Registry) Reschedule(hostServiceID int, spec string, j cron.Job) (cron.EntryID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.schedule(hostServiceID, spec, j)
+}
+
+// schedule adds the new entry before removing the old one, so a bad spec leaves the
+// host service scheduled as it was. The caller holds the lock.
+func (r *Registry) schedule(hostServiceID int, spec string, j cron.Job) (cron.EntryID, error) {
+	id, err := r.scheduler.AddJob(spec, j)
+	if err != nil {
+		return 0, err
+	}
+
+	if e, ok := r.entries[hostServiceID]; ok {
+		r.scheduler.Remove(e.id)
+	}
+	r.entries[hostServiceID] = entry{spec: spec, id: id}
+	return id, nil
+}
+
+// Remove stops scheduling a host service, and reports whether it was scheduled
+func (r *Registry) Remove(hostServiceID int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[hostServiceID]
+	if !ok {
+		return false
+	}
+	r.scheduler.Remove(e.id)
+	delete(r.entries, hostServiceID)
+	return true
+}
+
+// RemoveAll stops scheduling every host service
+func (r *Registry) RemoveAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, e := range r.entries {
+		r.scheduler.Remove(e.id)
+		delete(r.entries, k)
+	}
+}
+
+// Scheduled reports whether a host service is scheduled
+func (r *Registry) Scheduled(hostServiceID int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.entries[hostServiceID]
+	return ok
+}
+
+// Next returns when a host service is checked next. It is the zero time if the host
+// service is not scheduled, or the scheduler is not running.
+func (r *Registry) Next(hostServiceID int) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[hostServiceID]
+	if !ok {
+		return time.Time{}
+	}
+	return r.scheduler.Entry(e.id).Next
+}
+
+// List returns a snapshot of the scheduled host services, ordered by host service id
+func (r *Registry) List() []Item {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := make([]Item, 0, len(r.entries))
+	for k, e := range r.entries {
+		items = append(items, Item{
+			HostServiceID: k,
+			Spec:          e.spec,
+			EntryID:       e.id,
+			Entry:         r.scheduler.Entry(e.id),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].HostServiceID < items[j].HostServiceID })
+	return items
+}
@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/robfig/cron/v3"
+)
+
+// noop is a check job that does nothing
+var noop = cron.FuncJob(func() {})
+
+func TestAddIsIdempotent(t *testing.T) {
+	scheduler := cron.New()
+	r := NewRegistry(scheduler)
+
+	first, err := r.Add(1, "@every 1m", noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := r.Add(1, "@every 1m", noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Errorf("adding on the same spec gave entry %d, want %d", again, first)
+	}
+	if n := len(scheduler.Entries()); n != 1 {
+		t.Errorf("scheduler has %d entries, want 1", n)
+	}
+
+	changed, err := r.Add(1, "@every 2m", noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == first {
+		t.Error("adding on another spec kept the old entry")
+	}
+	if n := len(scheduler.Entries()); n != 1 {
+		t.Errorf("scheduler has %d entries after a new spec, want 1", n)
+	}
+}
+
+func TestRescheduleBadSpec(t *testing.T) {
+	scheduler := cron.New()
+	r := NewRegistry(scheduler)
+
+	id, err := r.Add(1, "@every 1m", noop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reschedule(1, "not a spec", noop); err == nil {
+		t.Fatal("rescheduling on a bad spec did not fail")
+	}
+
+	items := r.List()
+	if len(items) != 1 || items[0].EntryID != id || items[0].Spec != "@every 1m" {
+		t.Errorf("got %+v, want the host service still on its old entry", items)
+	}
+	if scheduler.Entry(id).ID != id {
+		t.Error("old entry was removed from the scheduler")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	scheduler := cron.New()
+	r := NewRegistry(scheduler)
+
+	if _, err := r.Add(1, "@every 1m", noop); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Remove(1) {
+		t.Error("Remove of a scheduled host service returned false")
+	}
+	if r.Remove(1) {
+		t.Error("second Remove returned true")
+	}
+	if r.Scheduled(1) || len(scheduler.Entries()) != 0 {
+		t.Error("host service is still scheduled")
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	scheduler := cron.New()
+	scheduler.Start()
+	defer scheduler.Stop()
+	r := NewRegistry(scheduler)
+
+	const hostServices = 10
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := (w + i) % hostServices
+				switch i % 6 {
+				case 0:
+					_, _ = r.Add(id, "@every 1m", noop)
+				case 1:
+					_, _ = r.Reschedule(id, "@every 2m", noop)
+				case 2:
+					r.Remove(id)
+				case 3:
+					_ = r.Next(id)
+				case 4:
+					_ = r.List()
+				case 5:
+					if i%60 == 5 {
+						r.RemoveAll()
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// every host service the registry knows of has exactly one scheduler entry
+	items := r.List()
+	if n := len(scheduler.Entries()); n != len(items) {
+		t.Errorf("scheduler has %d entries, registry has %d", n, len(items))
+	}
+	for _, it := range items {
+		if r.Next(it.HostServiceID).IsZero() {
+			t.Errorf("host service %d has no next run", it.HostServiceID)
+		}
+	}
+
+	r.RemoveAll()
+	if n := len(scheduler.Entries()); n != 0 {
+		t.Errorf("scheduler has %d entries after RemoveAll", n)
+	}
+}
@@ -0,0 +1,253 @@
+package notifiers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// statusColours are the attachment colours used for each status
+var statusColours = map[string]string{
+	"healthy": "#2eb886",
+	"warning": "#daa038",
+	"problem": "#a30200",
+	"pending": "#cccccc",
+}
+
+func init() {
+	Register(NewChatNotifier())
+}
+
+// ChatMessage is an incoming-webhook message in the format Slack and Mattermost share
+type ChatMessage struct {
+	Channel     string           `json:"channel,omitempty"`
+	Username    string           `json:"username,omitempty"`
+	Text        string           `json:"text,omitempty"`
+	Attachments []ChatAttachment `json:"attachments,omitempty"`
+}
+
+// ChatAttachment is a coloured block of a chat message
+type ChatAttachment struct {
+	Fallback  string      `json:"fallback"`
+	Color     string      `json:"color,omitempty"`
+	Title     string      `json:"title"`
+	TitleLink string      `json:"title_link,omitempty"`
+	Text      string      `json:"text,omitempty"`
+	Fields    []ChatField `json:"fields,omitempty"`
+	Footer    string      `json:"footer,omitempty"`
+	Timestamp int64       `json:"ts,omitempty"`
+}
+
+// ChatField is a title and value pair shown in an attachment
+type ChatField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// flapState tracks the status changes of one host service while chat messages about
+// it are being collapsed
+type flapState struct {
+	until      time.Time
+	suppressed int
+	latest     Notification
+	timer      *time.Timer
+}
+
+// ChatNotifier posts status changes to Slack or Mattermost incoming webhooks. When a
+// service changes status again within chat_flap_minutes of the last message, the changes
+// are collapsed into a single summary posted when that window closes. The summary is a
+// message of its own: incoming webhooks can neither edit a message they posted nor
+// reply in its thread, since they never learn its id, so threading or updating would
+// need a bot token and each chat's own API.
+type ChatNotifier struct {
+	Client *http.Client
+
+	mu       sync.Mutex
+	flaps    map[int]*flapState
+	flapUnit time.Duration // how long a minute of chat_flap_minutes lasts; tests shorten it
+}
+
+// NewChatNotifier returns a chat notifier with the default client
+func NewChatNotifier() *ChatNotifier {
+	return &ChatNotifier{
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		flaps:    make(map[int]*flapState),
+		flapUnit: time.Minute,
+	}
+}
+
+// Name returns the notifier name
+func (c *ChatNotifier) Name() string {
+	return "chat"
+}
+
+// Enabled reports whether chat notifications are turned on and have somewhere to go
+func (c *ChatNotifier) Enabled(pm map[string]string) bool {
+	if pm["chat_enabled"] != "1" {
+		return false
+	}
+	for _, status := range []string{"healthy", "warning", "problem"} {
+		if url, _ := chatDestination(pm, status); url != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Notify posts the status change, or holds it back if the service is flapping
+func (c *ChatNotifier) Notify(pm map[string]string, n Notification) error {
+	if n.OldStatus == "pending" && n.NewStatus == "healthy" {
+		return nil
+	}
+
+	// escalations are not status changes, so are never collapsed
+	if n.Escalation != "" {
+		return c.post(pm, n, chatMessage(pm, n, n.Escalation))
+	}
+
+	window := time.Duration(chatFlapMinutes(pm)) * c.flapUnit
+	if window > 0 {
+		c.mu.Lock()
+		fs, ok := c.flaps[n.HostServiceID]
+		if ok && time.Now().Before(fs.until) {
+			fs.suppressed++
+			fs.latest = n
+			// pm is a copy made for this notification, so it is safe to keep
+			if fs.timer == nil {
+				fs.timer = time.AfterFunc(time.Until(fs.until), func() {
+					c.flushFlaps(pm, n.HostServiceID)
+				})
+			}
+			c.mu.Unlock()
+			return nil
+		}
+		c.flaps[n.HostServiceID] = &flapState{until: time.Now().Add(window)}
+		c.mu.Unlock()
+	}
+
+	return c.post(pm, n, chatMessage(pm, n, ""))
+}
+
+// flushFlaps posts one message summing up the changes held back for a host service,
+// and starts a new window so continued flapping keeps being collapsed
+func (c *ChatNotifier) flushFlaps(pm map[string]string, hostServiceID int) {
+	c.mu.Lock()
+	fs, ok := c.flaps[hostServiceID]
+	if !ok || fs.suppressed == 0 {
+		delete(c.flaps, hostServiceID)
+		c.mu.Unlock()
+		return
+	}
+	n, suppressed := fs.latest, fs.suppressed
+	window := time.Duration(chatFlapMinutes(pm)) * c.flapUnit
+	c.flaps[hostServiceID] = &flapState{until: time.Now().Add(window)}
+	c.mu.Unlock()
+
+	note := fmt.Sprintf("Flapping: changed status %d more times in the last %d minutes", suppressed, chatFlapMinutes(pm))
+	if err := c.post(pm, n, chatMessage(pm, n, note)); err != nil {
+		log.Printf("chat notification for host service %d failed: %s", hostServiceID, err)
+	}
+}
+
+// post sends a message to the webhook for the notification's new status
+func (c *ChatNotifier) post(pm map[string]string, n Notification, msg ChatMessage) error {
+	url, _ := chatDestination(pm, n.NewStatus)
+	if url == "" {
+		return nil
+	}
+	return SendChatMessage(c.Client, url, msg)
+}
+
+// chatMessage builds the message for a notification; note, if set, is added as text
+func chatMessage(pm map[string]string, n Notification, note string) ChatMessage {
+	_, channel := chatDestination(pm, n.NewStatus)
+
+	colour, ok := statusColours[n.NewStatus]
+	if !ok {
+		colour = statusColours["pending"]
+	}
+
+	return ChatMessage{
+		Channel:  channel,
+		Username: "go_watch",
+		Text:     note,
+		Attachments: []ChatAttachment{
+			{
+				Fallback:  n.Subject(),
+				Color:     colour,
+				Title:     n.Subject(),
+				TitleLink: n.Link,
+				Text:      n.Message,
+				Fields: []ChatField{
+					{Title: "Host", Value: n.HostName, Short: true},
+					{Title: "Service", Value: n.ServiceName, Short: true},
+					{Title: "Status", Value: fmt.Sprintf("%s → %s", n.OldStatus, n.NewStatus), Short: true},
+				},
+				Footer:    "go_watch",
+				Timestamp: n.Time.Unix(),
+			},
+		},
+	}
+}
+
+// chatDestination returns the webhook url and channel for a status. A url or channel set
+// for the status (e.g. chat_webhook_url_problem) overrides the default one.
+func chatDestination(pm map[string]string, status string) (string, string) {
+	url := pm["chat_webhook_url_"+status]
+	if url == "" {
+		url = pm["chat_webhook_url"]
+	}
+	channel := pm["chat_channel_"+status]
+	if channel == "" {
+		channel = pm["chat_channel"]
+	}
+	return url, channel
+}
+
+// chatFlapMinutes returns the flap window in minutes; zero turns collapsing off
+func chatFlapMinutes(pm map[string]string) int {
+	m, err := strconv.Atoi(pm["chat_flap_minutes"])
+	if err != nil || m < 0 {
+		return 0
+	}
+	return m
+}
+
+// SendChatMessage posts a message to a Slack or Mattermost incoming webhook
+func SendChatMessage(client *http.Client, url string, msg ChatMessage) error {
+	if url == "" {
+		return errors.New("no webhook url")
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("chat webhook returned %s: %s", resp.Status, respBody)
+	}
+	return nil
+}
+
+// SampleChatMessage returns the message posted by the settings page test button
+func SampleChatMessage(pm map[string]string, status string) ChatMessage {
+	n := SampleNotification(pm)
+	n.NewStatus = status
+	return chatMessage(pm, n, "This is a test message from go_watch")
+}
@@ -0,0 +1,203 @@
+package notifiers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chatPost is a message received by a fake chat webhook, with the path it was posted to
+type chatPost struct {
+	Path    string
+	Message ChatMessage
+}
+
+// chatEndpoint accepts incoming-webhook messages and hands them to the returned channel
+func chatEndpoint(t *testing.T) (*httptest.Server, <-chan chatPost) {
+	t.Helper()
+
+	received := make(chan chatPost, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg ChatMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, "invalid_payload", http.StatusBadRequest)
+			return
+		}
+		received <- chatPost{Path: r.URL.Path, Message: msg}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+// testChatNotifier returns a chat notifier whose flap minutes last unit
+func testChatNotifier(client *http.Client, unit time.Duration) *ChatNotifier {
+	c := NewChatNotifier()
+	c.Client = client
+	c.flapUnit = unit
+	return c
+}
+
+// nextPost waits for the endpoint to receive a message
+func nextPost(t *testing.T, received <-chan chatPost, within time.Duration) chatPost {
+	t.Helper()
+
+	select {
+	case p := <-received:
+		return p
+	case <-time.After(within):
+		t.Fatal("no chat message posted")
+	}
+	return chatPost{}
+}
+
+// noPost fails if the endpoint receives a message within d
+func noPost(t *testing.T, received <-chan chatPost, d time.Duration) {
+	t.Helper()
+
+	select {
+	case p := <-received:
+		t.Fatalf("posted %q to %s, want nothing", p.Message.Attachments[0].Title, p.Path)
+	case <-time.After(d):
+	}
+}
+
+// change returns a notification of a host service changing status
+func change(hostServiceID int, from, to string) Notification {
+	return Notification{HostServiceID: hostServiceID, HostName: "web1", ServiceName: "HTTP", OldStatus: from, NewStatus: to, Time: time.Now()}
+}
+
+func TestChatNotifier(t *testing.T) {
+	srv, received := chatEndpoint(t)
+	c := testChatNotifier(srv.Client(), time.Millisecond)
+
+	pm := map[string]string{
+		"chat_enabled":             "1",
+		"chat_webhook_url":         srv.URL + "/all",
+		"chat_channel":             "#ops",
+		"chat_webhook_url_problem": srv.URL + "/problems",
+		"chat_channel_problem":     "#oncall",
+	}
+	if !c.Enabled(pm) {
+		t.Fatal("chat notifier not enabled")
+	}
+
+	t.Run("problem goes to its own channel", func(t *testing.T) {
+		if err := c.Notify(pm, change(1, "healthy", "problem")); err != nil {
+			t.Fatal(err)
+		}
+		p := nextPost(t, received, time.Second)
+		if p.Path != "/problems" || p.Message.Channel != "#oncall" {
+			t.Errorf("posted to %s %s, want /problems #oncall", p.Path, p.Message.Channel)
+		}
+		a := p.Message.Attachments[0]
+		if a.Color != statusColours["problem"] || a.Title != "HTTP on web1 is problem" {
+			t.Errorf("unexpected attachment %+v", a)
+		}
+	})
+
+	t.Run("warning goes to the default channel", func(t *testing.T) {
+		if err := c.Notify(pm, change(2, "healthy", "warning")); err != nil {
+			t.Fatal(err)
+		}
+		p := nextPost(t, received, time.Second)
+		if p.Path != "/all" || p.Message.Channel != "#ops" {
+			t.Errorf("posted to %s %s, want /all #ops", p.Path, p.Message.Channel)
+		}
+	})
+
+	t.Run("first healthy check", func(t *testing.T) {
+		if err := c.Notify(pm, change(3, "pending", "healthy")); err != nil {
+			t.Fatal(err)
+		}
+		noPost(t, received, 50*time.Millisecond)
+	})
+}
+
+func TestChatNotifierCollapsesFlaps(t *testing.T) {
+	const unit = 40 * time.Millisecond // chat_flap_minutes of 1 lasts 40ms
+
+	srv, received := chatEndpoint(t)
+	pm := map[string]string{
+		"chat_enabled":      "1",
+		"chat_webhook_url":  srv.URL,
+		"chat_flap_minutes": "1",
+	}
+
+	t.Run("changes within the window are summed up when it closes", func(t *testing.T) {
+		c := testChatNotifier(srv.Client(), unit)
+
+		for _, n := range []Notification{change(1, "healthy", "problem"), change(1, "problem", "healthy"), change(1, "healthy", "problem")} {
+			if err := c.Notify(pm, n); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		first := nextPost(t, received, time.Second)
+		if first.Message.Text != "" || first.Message.Attachments[0].Title != "HTTP on web1 is problem" {
+			t.Errorf("first message is %q %q, want the first change alone", first.Message.Text, first.Message.Attachments[0].Title)
+		}
+
+		summary := nextPost(t, received, 10*unit)
+		if !strings.Contains(summary.Message.Text, "changed status 2 more times") {
+			t.Errorf("summary text is %q", summary.Message.Text)
+		}
+		if got := summary.Message.Attachments[0].Fields[2].Value; got != "healthy → problem" {
+			t.Errorf("summary shows %q, want the latest change", got)
+		}
+		noPost(t, received, 2*unit)
+	})
+
+	t.Run("a change after a quiet window is posted at once", func(t *testing.T) {
+		c := testChatNotifier(srv.Client(), unit)
+
+		if err := c.Notify(pm, change(1, "healthy", "warning")); err != nil {
+			t.Fatal(err)
+		}
+		nextPost(t, received, time.Second)
+		time.Sleep(2 * unit)
+
+		if err := c.Notify(pm, change(1, "warning", "healthy")); err != nil {
+			t.Fatal(err)
+		}
+		p := nextPost(t, received, unit/2)
+		if p.Message.Text != "" {
+			t.Errorf("posted a summary %q, want the change itself", p.Message.Text)
+		}
+	})
+
+	t.Run("windows are per host service", func(t *testing.T) {
+		c := testChatNotifier(srv.Client(), unit)
+
+		for _, id := range []int{1, 2} {
+			if err := c.Notify(pm, change(id, "healthy", "problem")); err != nil {
+				t.Fatal(err)
+			}
+			nextPost(t, received, unit/2)
+		}
+		time.Sleep(2 * unit)
+	})
+
+	t.Run("escalations are never collapsed", func(t *testing.T) {
+		c := testChatNotifier(srv.Client(), unit)
+
+		if err := c.Notify(pm, change(1, "healthy", "problem")); err != nil {
+			t.Fatal(err)
+		}
+		nextPost(t, received, time.Second)
+
+		escalated := change(1, "problem", "problem")
+		escalated.Escalation = "Escalated to step 2 of 2"
+		if err := c.Notify(pm, escalated); err != nil {
+			t.Fatal(err)
+		}
+		p := nextPost(t, received, unit/2)
+		if p.Message.Text != escalated.Escalation {
+			t.Errorf("posted %q, want the escalation", p.Message.Text)
+		}
+		time.Sleep(2 * unit)
+	})
+}
@@ -0,0 +1,87 @@
+package notifiers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+
+	"github.com/brianmaksy/go-watch/internal/channeldata"
+	"github.com/brianmaksy/go-watch/internal/helpers"
+)
+
+func init() {
+	Register(EmailNotifier{Send: helpers.SendEmail})
+}
+
+var emailContent = template.Must(template.New("status-change").Parse(`
+{{if .Escalation}}
+<p><strong>{{.ServiceName}}</strong> on <strong>{{.HostName}}</strong> is <strong>{{.NewStatus}}</strong>
+and nobody has acknowledged it. {{.Escalation}}</p>
+{{else}}
+<p><strong>{{.ServiceName}}</strong> on <strong>{{.HostName}}</strong> has changed from
+<strong>{{.OldStatus}}</strong> to <strong>{{.NewStatus}}</strong>.</p>
+{{end}}
+<p>{{.Message}}</p>
+<p>{{.Time.Format "2006-01-02 3:04:05 PM"}}</p>
+{{if .Link}}<p><a href="{{.Link}}">{{.Link}}</a></p>{{end}}
+`))
+
+// EmailNotifier queues an email to whoever is on call, or else to the notify_email
+// address, via the mail dispatcher
+type EmailNotifier struct {
+	Send func(channeldata.MailData)
+}
+
+// Name returns the notifier name
+func (e EmailNotifier) Name() string {
+	return "email"
+}
+
+// Enabled reports whether email notifications are turned on in settings
+func (e EmailNotifier) Enabled(pm map[string]string) bool {
+	return pm["notify_via_email"] == "1"
+}
+
+// Notify queues the notification email. Services coming up healthy for the first time
+// are not worth an email.
+func (e EmailNotifier) Notify(pm map[string]string, n Notification) error {
+	if n.OldStatus == "pending" && n.NewStatus == "healthy" {
+		return nil
+	}
+
+	sent := false
+	for _, r := range n.OnCall {
+		if r.Email == "" {
+			continue
+		}
+		if err := e.NotifyAddress(r.Name, r.Email, n); err != nil {
+			return err
+		}
+		sent = true
+	}
+	if sent {
+		return nil
+	}
+
+	if pm["notify_email"] == "" {
+		return errors.New("no notify_email address set")
+	}
+	return e.NotifyAddress(pm["notify_name"], pm["notify_email"], n)
+}
+
+// NotifyAddress queues the notification email to a given address, e.g. a user an
+// incident is escalated to
+func (e EmailNotifier) NotifyAddress(toName, toAddress string, n Notification) error {
+	var content bytes.Buffer
+	if err := emailContent.Execute(&content, n); err != nil {
+		return err
+	}
+
+	e.Send(channeldata.MailData{
+		ToName:    toName,
+		ToAddress: toAddress,
+		Subject:   n.Subject(),
+		Content:   template.HTML(content.String()),
+	})
+	return nil
+}
@@ -0,0 +1,159 @@
+package notifiers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notification describes a change in the status of a host service
+type Notification struct {
+	HostID        int
+	HostServiceID int
+	HostName      string
+	ServiceName   string
+	OldStatus     string
+	NewStatus     string
+	Message       string
+	Link          string // link back to the host page, built from the site_url preference
+	Time          time.Time
+
+	// PagerDutyRoutingKey is the host's own routing key, if it has one
+	PagerDutyRoutingKey string
+
+	// Escalation is set when the notification escalates, or reminds people of, an
+	// incident nobody has acknowledged, rather than reporting a status change
+	Escalation string
+
+	// OnCall is who is on call for the host. Email and text messages go to them instead
+	// of the addresses in settings.
+	OnCall []Recipient
+}
+
+// Recipient is a person a notification is addressed to
+type Recipient struct {
+	Name  string
+	Email string
+	Phone string
+}
+
+// Subject returns a one line summary of the notification
+func (n Notification) Subject() string {
+	if n.Escalation != "" {
+		return fmt.Sprintf("Unacknowledged: %s on %s is %s", n.ServiceName, n.HostName, n.NewStatus)
+	}
+	return fmt.Sprintf("%s on %s is %s", n.ServiceName, n.HostName, n.NewStatus)
+}
+
+// Notifier sends notifications through one channel (email, sms, ...). Enabled and
+// Notify get a copy of the current preference map, so settings changes apply to the
+// next notification, and a notifier may keep the map for as long as it needs it.
+type Notifier interface {
+	Name() string
+	Enabled(pm map[string]string) bool
+	Notify(pm map[string]string, n Notification) error
+}
+
+var (
+	mu        sync.RWMutex
+	notifiers []Notifier
+)
+
+// Register adds a notifier to the ones Send uses
+func Register(n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifiers = append(notifiers, n)
+}
+
+// Send passes a notification to every enabled notifier. Each runs in its own
+// goroutine so a slow channel never holds up a check; failures are logged. The
+// notifiers get a copy of pm, as they may still be using it after Send returns.
+func Send(pm map[string]string, n Notification) {
+	pm = copyPreferences(pm)
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, x := range notifiers {
+		if !x.Enabled(pm) {
+			continue
+		}
+		go func(x Notifier) {
+			if err := x.Notify(pm, n); err != nil {
+				log.Printf("%s notification for host service %d failed: %s", x.Name(), n.HostServiceID, err)
+			}
+		}(x)
+	}
+}
+
+// SendVia passes a notification to the named notifiers only, if they are enabled
+func SendVia(pm map[string]string, names []string, n Notification) {
+	pm = copyPreferences(pm)
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, x := range notifiers {
+		if !x.Enabled(pm) || !inList(x.Name(), names) {
+			continue
+		}
+		go func(x Notifier) {
+			if err := x.Notify(pm, n); err != nil {
+				log.Printf("%s notification for host service %d failed: %s", x.Name(), n.HostServiceID, err)
+			}
+		}(x)
+	}
+}
+
+// Names returns the names of the registered notifiers
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, len(notifiers))
+	for i, x := range notifiers {
+		names[i] = x.Name()
+	}
+	return names
+}
+
+// copyPreferences returns a copy of the preference map
+func copyPreferences(pm map[string]string) map[string]string {
+	c := make(map[string]string, len(pm))
+	for k, v := range pm {
+		c[k] = v
+	}
+	return c
+}
+
+// inList reports whether s is in list
+func inList(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// HostLink returns the url of a host's page, or an empty string if site_url is not set
+func HostLink(pm map[string]string, hostID int) string {
+	siteURL := strings.TrimSuffix(pm["site_url"], "/")
+	if siteURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/admin/host/%d", siteURL, hostID)
+}
+
+// IncidentLink returns the url of an incident's page, or an empty string if site_url is
+// not set
+func IncidentLink(pm map[string]string, incidentID int) string {
+	siteURL := strings.TrimSuffix(pm["site_url"], "/")
+	if siteURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/admin/incidents/%d", siteURL, incidentID)
+}
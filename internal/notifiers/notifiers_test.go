@@ -0,0 +1,62 @@
+package notifiers
+
+import (
+	"testing"
+	"time"
+)
+
+// recorder is a notifier that hands the preferences it is given to a channel
+type recorder struct {
+	name string
+	got  chan map[string]string
+}
+
+func (r recorder) Name() string                      { return r.name }
+func (r recorder) Enabled(pm map[string]string) bool { return pm[r.name] == "1" }
+
+func (r recorder) Notify(pm map[string]string, n Notification) error {
+	// read the map a while after Send has returned, as chat's flap timer does
+	time.Sleep(10 * time.Millisecond)
+	r.got <- map[string]string{r.name: pm[r.name], "site_url": pm["site_url"]}
+	return nil
+}
+
+func TestSendCopiesPreferences(t *testing.T) {
+	first := recorder{name: "recorder-first", got: make(chan map[string]string, 1)}
+	second := recorder{name: "recorder-second", got: make(chan map[string]string, 1)}
+	Register(first)
+	Register(second)
+
+	pm := map[string]string{"recorder-first": "1", "recorder-second": "1", "site_url": "http://before"}
+	Send(pm, Notification{HostServiceID: 1})
+	// as saving settings does, while the notifiers are still running
+	pm["site_url"] = "http://after"
+
+	for _, r := range []recorder{first, second} {
+		select {
+		case got := <-r.got:
+			if got["site_url"] != "http://before" {
+				t.Errorf("%s saw site_url %q, want the value when Send was called", r.name, got["site_url"])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not notified", r.name)
+		}
+	}
+
+	SendVia(pm, []string{"recorder-second"}, Notification{HostServiceID: 1})
+	pm["site_url"] = "http://later"
+
+	select {
+	case got := <-second.got:
+		if got["site_url"] != "http://after" {
+			t.Errorf("SendVia passed site_url %q, want the value when it was called", got["site_url"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("recorder-second was not notified by SendVia")
+	}
+	select {
+	case <-first.got:
+		t.Error("SendVia notified a notifier that was not named")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,153 @@
+package notifiers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultPagerDutyEventsURL is the PagerDuty Events API v2
+const defaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+func init() {
+	Register(PagerDutyNotifier{Client: &http.Client{Timeout: 10 * time.Second}})
+}
+
+// PagerDutyEvent is an event sent to the PagerDuty Events API v2
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+	Client      string            `json:"client,omitempty"`
+	ClientURL   string            `json:"client_url,omitempty"`
+	Links       []PagerDutyLink   `json:"links,omitempty"`
+}
+
+// PagerDutyPayload describes the alert of a trigger event
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// PagerDutyLink is a link shown on the PagerDuty incident
+type PagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// pagerDutyResponse is the body PagerDuty returns for an event
+type pagerDutyResponse struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Errors  []string `json:"errors"`
+}
+
+// PagerDutyNotifier triggers a PagerDuty incident when a service goes into problem and
+// resolves it when the service is healthy again
+type PagerDutyNotifier struct {
+	Client *http.Client
+
+	// EventsURL is where events are posted, and the real Events API if empty. It is
+	// only set by tests, so routing keys are never sent anywhere else.
+	EventsURL string
+}
+
+// Name returns the notifier name
+func (p PagerDutyNotifier) Name() string {
+	return "pagerduty"
+}
+
+// Enabled reports whether PagerDuty is turned on in settings. Hosts can have their
+// own routing key, so a global one is not required.
+func (p PagerDutyNotifier) Enabled(pm map[string]string) bool {
+	return pm["pagerduty_enabled"] == "1"
+}
+
+// Notify sends a trigger or resolve event, if the transition calls for one
+func (p PagerDutyNotifier) Notify(pm map[string]string, n Notification) error {
+	routingKey := n.PagerDutyRoutingKey
+	if routingKey == "" {
+		routingKey = pm["pagerduty_routing_key"]
+	}
+	if routingKey == "" {
+		return nil
+	}
+
+	var action string
+	switch {
+	case n.NewStatus == "problem":
+		action = "trigger"
+	case n.NewStatus == "healthy" && n.OldStatus != "pending":
+		action = "resolve"
+	default:
+		return nil
+	}
+
+	event := PagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: action,
+		DedupKey:    PagerDutyDedupKey(n.HostServiceID),
+	}
+
+	if action == "trigger" {
+		event.Payload = &PagerDutyPayload{
+			Summary:   n.Subject(),
+			Source:    n.HostName,
+			Severity:  "critical",
+			Timestamp: n.Time.Format(time.RFC3339),
+			Component: n.ServiceName,
+			CustomDetails: map[string]string{
+				"message":    n.Message,
+				"old_status": n.OldStatus,
+				"new_status": n.NewStatus,
+			},
+		}
+		event.Client = "go_watch"
+		if n.Link != "" {
+			event.ClientURL = n.Link
+			event.Links = []PagerDutyLink{{Href: n.Link, Text: "View host in go_watch"}}
+		}
+	}
+
+	eventsURL := p.EventsURL
+	if eventsURL == "" {
+		eventsURL = defaultPagerDutyEventsURL
+	}
+	return SendPagerDutyEvent(p.Client, eventsURL, event)
+}
+
+// PagerDutyDedupKey returns the dedup key for a host service, so that the resolve
+// event matches the incident the trigger opened
+func PagerDutyDedupKey(hostServiceID int) string {
+	return fmt.Sprintf("go-watch-host-service-%d", hostServiceID)
+}
+
+// SendPagerDutyEvent posts an event to the PagerDuty Events API v2
+func SendPagerDutyEvent(client *http.Client, eventsURL string, event PagerDutyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(eventsURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var pr pagerDutyResponse
+		if err := json.NewDecoder(resp.Body).Decode(&pr); err == nil && pr.Message != "" {
+			return fmt.Errorf("pagerduty returned %s: %s %v", resp.Status, pr.Message, pr.Errors)
+		}
+		return fmt.Errorf("pagerduty returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package notifiers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePagerDuty records the events posted to the PagerDuty Events API
+type fakePagerDuty struct {
+	mu     sync.Mutex
+	events []PagerDutyEvent
+}
+
+func (f *fakePagerDuty) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var event PagerDutyEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil || event.RoutingKey == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status": "invalid event", "message": "Event object is invalid", "errors": ["bad"]}`))
+		return
+	}
+
+	f.mu.Lock()
+	f.events = append(f.events, event)
+	f.mu.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte(`{"status": "success", "message": "Event processed", "dedup_key": "` + event.DedupKey + `"}`))
+}
+
+// sent returns the events posted since the last call
+func (f *fakePagerDuty) sent() []PagerDutyEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := f.events
+	f.events = nil
+	return e
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	fake := &fakePagerDuty{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	pm := map[string]string{
+		"pagerduty_enabled":     "1",
+		"pagerduty_routing_key": "global-key",
+	}
+	p := PagerDutyNotifier{Client: srv.Client(), EventsURL: srv.URL}
+
+	problem := Notification{
+		HostServiceID: 42,
+		HostName:      "web1",
+		ServiceName:   "HTTP",
+		OldStatus:     "healthy",
+		NewStatus:     "problem",
+		Message:       "http://web1 - 500 Internal Server Error",
+		Link:          "http://go-watch.test/admin/host/1",
+		Time:          time.Now(),
+	}
+
+	t.Run("trigger on problem", func(t *testing.T) {
+		if err := p.Notify(pm, problem); err != nil {
+			t.Fatal(err)
+		}
+		sent := fake.sent()
+		if len(sent) != 1 {
+			t.Fatalf("sent %d events, want 1", len(sent))
+		}
+		e := sent[0]
+		if e.EventAction != "trigger" || e.DedupKey != "go-watch-host-service-42" || e.RoutingKey != "global-key" {
+			t.Errorf("sent %s with dedup key %s and routing key %s", e.EventAction, e.DedupKey, e.RoutingKey)
+		}
+		if e.Payload == nil || e.Payload.Severity != "critical" || e.Payload.Source != "web1" || e.Payload.Summary != problem.Subject() {
+			t.Errorf("unexpected payload %+v", e.Payload)
+		}
+		if e.ClientURL != problem.Link {
+			t.Errorf("client url %q, want %q", e.ClientURL, problem.Link)
+		}
+	})
+
+	t.Run("resolve on recovery", func(t *testing.T) {
+		recovered := problem
+		recovered.OldStatus, recovered.NewStatus = "problem", "healthy"
+		if err := p.Notify(pm, recovered); err != nil {
+			t.Fatal(err)
+		}
+		sent := fake.sent()
+		if len(sent) != 1 {
+			t.Fatalf("sent %d events, want 1", len(sent))
+		}
+		if e := sent[0]; e.EventAction != "resolve" || e.DedupKey != "go-watch-host-service-42" || e.Payload != nil {
+			t.Errorf("sent %s with dedup key %s and payload %+v", e.EventAction, e.DedupKey, e.Payload)
+		}
+	})
+
+	t.Run("host routing key", func(t *testing.T) {
+		own := problem
+		own.PagerDutyRoutingKey = "host-key"
+		if err := p.Notify(pm, own); err != nil {
+			t.Fatal(err)
+		}
+		if sent := fake.sent(); len(sent) != 1 || sent[0].RoutingKey != "host-key" {
+			t.Errorf("sent %+v, want one event with the host's routing key", sent)
+		}
+	})
+
+	t.Run("no event", func(t *testing.T) {
+		for _, n := range []Notification{
+			{HostServiceID: 42, OldStatus: "pending", NewStatus: "healthy"},
+			{HostServiceID: 42, OldStatus: "healthy", NewStatus: "warning"},
+		} {
+			if err := p.Notify(pm, n); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if sent := fake.sent(); len(sent) != 0 {
+			t.Errorf("sent %d events, want none", len(sent))
+		}
+	})
+}
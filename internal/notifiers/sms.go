@@ -0,0 +1,121 @@
+package notifiers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultTwilioBaseURL is the Twilio REST API
+const defaultTwilioBaseURL = "https://api.twilio.com"
+
+func init() {
+	Register(SMSNotifier{Client: &http.Client{Timeout: 10 * time.Second}})
+}
+
+// SMSNotifier sends a text message through Twilio when a service goes into problem
+// or recovers from it, to whoever is on call or else to the sms_notify_number
+type SMSNotifier struct {
+	Client *http.Client
+
+	// BaseURL is where the Twilio REST API is, and the real one if empty. It is only
+	// set by tests, so credentials are never sent anywhere else.
+	BaseURL string
+}
+
+// Name returns the notifier name
+func (s SMSNotifier) Name() string {
+	return "sms"
+}
+
+// Enabled reports whether text message notifications are turned on and configured
+func (s SMSNotifier) Enabled(pm map[string]string) bool {
+	return pm["sms_enabled"] == "1" &&
+		pm["notify_via_sms"] == "1" &&
+		pm["sms_provider"] == "twilio"
+}
+
+// Notify sends the text message. Only problem and recovery transitions are sent;
+// warnings would burn through texts without anyone needing to act.
+func (s SMSNotifier) Notify(pm map[string]string, n Notification) error {
+	if n.NewStatus != "problem" && !(n.NewStatus == "healthy" && n.OldStatus == "problem") {
+		return nil
+	}
+
+	body := fmt.Sprintf("go_watch: %s - %s", n.Subject(), n.Message)
+	if n.Link != "" {
+		body = fmt.Sprintf("%s %s", body, n.Link)
+	}
+
+	sent := false
+	for _, r := range n.OnCall {
+		if r.Phone == "" {
+			continue
+		}
+		if err := s.Send(pm, r.Phone, body); err != nil {
+			return err
+		}
+		sent = true
+	}
+	if sent {
+		return nil
+	}
+
+	return s.Send(pm, pm["sms_notify_number"], body)
+}
+
+// twilioError is the error body returned by the Twilio REST API
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send sends a text message using the Twilio Messages API and the credentials in the
+// preference map
+func (s SMSNotifier) Send(pm map[string]string, to, body string) error {
+	sid := pm["twilio_sid"]
+	if sid == "" || pm["twilio_auth_token"] == "" || pm["twilio_phone_number"] == "" {
+		return errors.New("twilio sid, auth token and phone number are required")
+	}
+	if to == "" {
+		return errors.New("no number to send to")
+	}
+
+	baseURL := strings.TrimSuffix(s.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultTwilioBaseURL
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", baseURL, url.PathEscape(sid))
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", pm["twilio_phone_number"])
+	form.Set("Body", body)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(sid, pm["twilio_auth_token"])
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var te twilioError
+		if err := json.NewDecoder(resp.Body).Decode(&te); err == nil && te.Message != "" {
+			return fmt.Errorf("twilio error %d: %s", te.Code, te.Message)
+		}
+		return fmt.Errorf("twilio returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package notifiers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeTwilio records the messages posted to the Twilio Messages API
+type fakeTwilio struct {
+	mu       sync.Mutex
+	messages []map[string]string
+}
+
+func (f *fakeTwilio) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sid, token, ok := r.BasicAuth()
+	if !ok || sid != "AC123" || token != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code": 20003, "message": "Authenticate"}`))
+		return
+	}
+	if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.messages = append(f.messages, map[string]string{
+		"To":   r.PostForm.Get("To"),
+		"From": r.PostForm.Get("From"),
+		"Body": r.PostForm.Get("Body"),
+	})
+	f.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(`{"sid": "SM1", "status": "queued"}`))
+}
+
+// sent returns the messages posted since the last call
+func (f *fakeTwilio) sent() []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.messages
+	f.messages = nil
+	return m
+}
+
+func TestSMSNotifier(t *testing.T) {
+	fake := &fakeTwilio{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	pm := map[string]string{
+		"sms_enabled":         "1",
+		"notify_via_sms":      "1",
+		"sms_provider":        "twilio",
+		"twilio_sid":          "AC123",
+		"twilio_auth_token":   "secret",
+		"twilio_phone_number": "+15550000000",
+		"sms_notify_number":   "+15551111111",
+	}
+	s := SMSNotifier{Client: srv.Client(), BaseURL: srv.URL}
+	if !s.Enabled(pm) {
+		t.Fatal("sms notifier not enabled")
+	}
+
+	problem := Notification{HostServiceID: 1, HostName: "web1", ServiceName: "HTTP", OldStatus: "healthy", NewStatus: "problem"}
+
+	tests := []struct {
+		name string
+		n    Notification
+		to   []string
+	}{
+		{"on call phone first", withOnCall(problem, Recipient{Name: "Ann", Phone: "+15552222222"}, Recipient{Name: "Bob"}), []string{"+15552222222"}},
+		{"notify number when nobody on call has a phone", withOnCall(problem, Recipient{Name: "Bob", Email: "bob@example.test"}), []string{"+15551111111"}},
+		{"recovery", Notification{HostServiceID: 1, OldStatus: "problem", NewStatus: "healthy"}, []string{"+15551111111"}},
+		{"pending to healthy", Notification{HostServiceID: 1, OldStatus: "pending", NewStatus: "healthy"}, nil},
+		{"warning", Notification{HostServiceID: 1, OldStatus: "healthy", NewStatus: "warning"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Notify(pm, tt.n); err != nil {
+				t.Fatal(err)
+			}
+
+			sent := fake.sent()
+			if len(sent) != len(tt.to) {
+				t.Fatalf("sent %d messages, want %d", len(sent), len(tt.to))
+			}
+			for i, m := range sent {
+				if m["To"] != tt.to[i] || m["From"] != "+15550000000" {
+					t.Errorf("sent from %s to %s, want from +15550000000 to %s", m["From"], m["To"], tt.to[i])
+				}
+			}
+		})
+	}
+
+	t.Run("twilio error", func(t *testing.T) {
+		bad := copyPreferences(pm)
+		bad["twilio_auth_token"] = "wrong"
+		if err := s.Notify(bad, problem); err == nil || err.Error() != "twilio error 20003: Authenticate" {
+			t.Errorf("got error %v, want the twilio error", err)
+		}
+	})
+}
+
+// withOnCall returns n addressed to the given recipients
+func withOnCall(n Notification, recipients ...Recipient) Notification {
+	n.OnCall = recipients
+	return n
+}
@@ -0,0 +1,293 @@
+package notifiers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/httpheaders"
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed with the webhook secret
+	SignatureHeader = "X-Go-Watch-Signature"
+
+	// DefaultWebhookTemplate is the body sent by webhooks without a template of their own
+	DefaultWebhookTemplate = `{
+    "host_id": {{.HostID}},
+    "host_service_id": {{.HostServiceID}},
+    "host": {{json .HostName}},
+    "service": {{json .ServiceName}},
+    "old_status": {{json .OldStatus}},
+    "status": {{json .NewStatus}},
+    "message": {{json .Message}},
+    "link": {{json .Link}},
+    "time": {{json .Time}}
+}`
+
+	// maxResponseBody is how much of a webhook's response is kept in the delivery log
+	maxResponseBody = 1024
+)
+
+// WebhookStore is the part of the database repository the webhook notifier needs
+type WebhookStore interface {
+	AllWebhooks() ([]models.Webhook, error)
+	InsertWebhookDelivery(d models.WebhookDelivery) (int, error)
+	UpdateWebhookDelivery(d models.WebhookDelivery) error
+}
+
+// WebhookNotifier posts a templated json body to every active webhook. Failed deliveries
+// are retried with exponential backoff, and every delivery is logged to the database.
+type WebhookNotifier struct {
+	Store       WebhookStore
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     time.Duration // wait before the first retry; doubled for each one after
+}
+
+// NewWebhookNotifier returns a webhook notifier with the default client and retry policy
+func NewWebhookNotifier(store WebhookStore) WebhookNotifier {
+	return WebhookNotifier{
+		Store:       store,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 5,
+		Backoff:     2 * time.Second,
+	}
+}
+
+// Name returns the notifier name
+func (w WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Enabled reports whether there is an active webhook with a url to deliver to. Each
+// webhook is switched on or off by its own active flag.
+func (w WebhookNotifier) Enabled(pm map[string]string) bool {
+	webhooks, err := w.deliverable()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return len(webhooks) > 0
+}
+
+// Notify delivers the notification to every active webhook, each in its own goroutine
+// so one slow endpoint does not delay the others
+func (w WebhookNotifier) Notify(pm map[string]string, n Notification) error {
+	webhooks, err := w.deliverable()
+	if err != nil {
+		return err
+	}
+
+	for _, wh := range webhooks {
+		go func(wh models.Webhook) {
+			if _, err := w.Deliver(wh, n, w.MaxAttempts); err != nil {
+				log.Printf("webhook %s for host service %d failed: %s", wh.Name, n.HostServiceID, err)
+			}
+		}(wh)
+	}
+	return nil
+}
+
+// deliverable returns the active webhooks that have a url
+func (w WebhookNotifier) deliverable() ([]models.Webhook, error) {
+	if w.Store == nil {
+		return nil, nil
+	}
+	webhooks, err := w.Store.AllWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	var active []models.Webhook
+	for _, wh := range webhooks {
+		if wh.Active == 1 && strings.TrimSpace(wh.URL) != "" {
+			active = append(active, wh)
+		}
+	}
+	return active, nil
+}
+
+// Deliver sends one notification to a webhook, making up to attempts tries, and returns
+// the delivery as logged
+func (w WebhookNotifier) Deliver(wh models.Webhook, n Notification, attempts int) (models.WebhookDelivery, error) {
+	d := models.WebhookDelivery{
+		WebhookID:     wh.ID,
+		WebhookName:   wh.Name,
+		HostServiceID: n.HostServiceID,
+		Event:         n.NewStatus,
+		Status:        "pending",
+	}
+
+	body, err := RenderWebhookBody(wh.BodyTemplate, n)
+	if err != nil {
+		d.Status = "failed"
+		d.Error = err.Error()
+		w.logDelivery(&d)
+		return d, err
+	}
+	d.Payload = string(body)
+
+	headers, err := httpheaders.Parse(wh.Headers)
+	if err != nil {
+		d.Status = "failed"
+		d.Error = err.Error()
+		w.logDelivery(&d)
+		return d, err
+	}
+
+	w.logDelivery(&d)
+
+	wait := w.Backoff
+	for d.Attempts < attempts {
+		d.Attempts++
+
+		retry, err := w.post(wh, headers, body, &d)
+		if err == nil {
+			d.Status = "delivered"
+			d.Error = ""
+			w.logDelivery(&d)
+			return d, nil
+		}
+
+		d.Error = err.Error()
+		if !retry || d.Attempts >= attempts {
+			d.Status = "failed"
+			w.logDelivery(&d)
+			return d, err
+		}
+
+		d.Status = "retrying"
+		w.logDelivery(&d)
+		time.Sleep(wait)
+		wait *= 2
+	}
+
+	return d, errors.New("no delivery attempts made")
+}
+
+// post makes a single delivery attempt. It reports whether a failure is worth retrying:
+// connection errors, 429 and 5xx responses are; any other non-2xx response is not.
+func (w WebhookNotifier) post(wh models.Webhook, headers http.Header, body []byte, d *models.WebhookDelivery) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-watch-webhook")
+	req.Header.Set("X-Go-Watch-Event", "host-service-status-changed")
+	req.Header.Set("X-Go-Watch-Delivery", fmt.Sprintf("%d", d.ID))
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	if wh.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+SignWebhookBody(wh.Secret, body))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		d.ResponseCode = 0
+		d.ResponseBody = ""
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	d.ResponseCode = resp.StatusCode
+	d.ResponseBody = string(respBody)
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// logDelivery inserts the delivery the first time it is called and updates it after that
+func (w WebhookNotifier) logDelivery(d *models.WebhookDelivery) {
+	if w.Store == nil {
+		return
+	}
+
+	if d.ID == 0 {
+		id, err := w.Store.InsertWebhookDelivery(*d)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		d.ID = id
+		return
+	}
+
+	if err := w.Store.UpdateWebhookDelivery(*d); err != nil {
+		log.Println(err)
+	}
+}
+
+// RenderWebhookBody executes a webhook body template against a notification and checks
+// that the result is valid json. The template can use the json function to quote values.
+func RenderWebhookBody(tmpl string, n Notification) ([]byte, error) {
+	if tmpl == "" {
+		tmpl = DefaultWebhookTemplate
+	}
+
+	t, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, n); err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("webhook template does not produce valid json")
+	}
+	return buf.Bytes(), nil
+}
+
+// SignWebhookBody returns the hex encoded HMAC-SHA256 of body, keyed with secret
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SampleNotification returns a made up notification, used to test webhook templates
+// and endpoints
+func SampleNotification(pm map[string]string) Notification {
+	return Notification{
+		HostID:        1,
+		HostServiceID: 1,
+		HostName:      "example.com",
+		ServiceName:   "HTTP",
+		OldStatus:     "healthy",
+		NewStatus:     "problem",
+		Message:       "this is a test notification from go_watch",
+		Link:          HostLink(pm, 1),
+		Time:          time.Now(),
+	}
+}
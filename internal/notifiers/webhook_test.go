@@ -0,0 +1,311 @@
+package notifiers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// fakeWebhookStore keeps webhooks and the delivery log in memory
+type fakeWebhookStore struct {
+	mu         sync.Mutex
+	webhooks   []models.Webhook
+	deliveries []models.WebhookDelivery // every state a delivery was logged in, in order
+}
+
+func (f *fakeWebhookStore) AllWebhooks() ([]models.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.webhooks, nil
+}
+
+func (f *fakeWebhookStore) InsertWebhookDelivery(d models.WebhookDelivery) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d.ID = len(f.deliveries) + 1
+	f.deliveries = append(f.deliveries, d)
+	return d.ID, nil
+}
+
+func (f *fakeWebhookStore) UpdateWebhookDelivery(d models.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries = append(f.deliveries, d)
+	return nil
+}
+
+// statuses returns the statuses deliveries were logged with
+func (f *fakeWebhookStore) statuses() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var s []string
+	for _, d := range f.deliveries {
+		s = append(s, d.Status)
+	}
+	return s
+}
+
+// webhookRequest is a request received by a fake webhook endpoint
+type webhookRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// webhookEndpoint answers with each of codes in turn, and then with the last of them,
+// and hands every request it receives to the returned channel
+func webhookEndpoint(t *testing.T, codes ...int) (*httptest.Server, <-chan webhookRequest) {
+	t.Helper()
+
+	received := make(chan webhookRequest, 10)
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- webhookRequest{Header: r.Header, Body: body}
+
+		mu.Lock()
+		code := codes[len(codes)-1]
+		if calls < len(codes) {
+			code = codes[calls]
+		}
+		calls++
+		mu.Unlock()
+
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+// testWebhookNotifier returns a notifier that retries at once, so tests do not wait
+func testWebhookNotifier(store WebhookStore, client *http.Client) WebhookNotifier {
+	return WebhookNotifier{Store: store, Client: client, MaxAttempts: 3, Backoff: time.Millisecond}
+}
+
+var webhookNotification = Notification{
+	HostID:        1,
+	HostServiceID: 7,
+	HostName:      "web1",
+	ServiceName:   "HTTP",
+	OldStatus:     "healthy",
+	NewStatus:     "problem",
+	Message:       "http://web1 - 500 Internal Server Error",
+	Link:          "http://go-watch.test/admin/host/1",
+	Time:          time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+}
+
+func TestWebhookSignature(t *testing.T) {
+	srv, received := webhookEndpoint(t, http.StatusOK)
+	w := testWebhookNotifier(&fakeWebhookStore{}, srv.Client())
+
+	wh := models.Webhook{ID: 1, Name: "ops", URL: srv.URL, Secret: "s3cret", Headers: "X-Team: ops", Active: 1}
+	if _, err := w.Deliver(wh, webhookNotification, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-received
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(r.Body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := r.Header.Get(SignatureHeader); got != want {
+		t.Errorf("signature is %q, want %q", got, want)
+	}
+	if r.Header.Get("X-Team") != "ops" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+
+	t.Run("unsigned without a secret", func(t *testing.T) {
+		wh.Secret = ""
+		if _, err := w.Deliver(wh, webhookNotification, 1); err != nil {
+			t.Fatal(err)
+		}
+		if got := (<-received).Header.Get(SignatureHeader); got != "" {
+			t.Errorf("sent signature %q without a secret", got)
+		}
+	})
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []int
+		status   string
+		attempts int
+		logged   []string
+	}{
+		{"delivered first time", []int{200}, "delivered", 1, []string{"pending", "delivered"}},
+		{"retried after 5xx", []int{503, 502, 200}, "delivered", 3, []string{"pending", "retrying", "retrying", "delivered"}},
+		{"retried after 429", []int{429, 204}, "delivered", 2, []string{"pending", "retrying", "delivered"}},
+		{"gives up after max attempts", []int{500}, "failed", 3, []string{"pending", "retrying", "retrying", "failed"}},
+		{"not retried after 4xx", []int{400}, "failed", 1, []string{"pending", "failed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, received := webhookEndpoint(t, tt.codes...)
+			store := &fakeWebhookStore{}
+			w := testWebhookNotifier(store, srv.Client())
+
+			d, err := w.Deliver(models.Webhook{ID: 1, Name: "ops", URL: srv.URL, Active: 1}, webhookNotification, w.MaxAttempts)
+			if (err == nil) != (tt.status == "delivered") {
+				t.Errorf("got error %v for a %s delivery", err, tt.status)
+			}
+			if d.Status != tt.status || d.Attempts != tt.attempts {
+				t.Errorf("delivery %s after %d attempts, want %s after %d", d.Status, d.Attempts, tt.status, tt.attempts)
+			}
+			if n := len(received); n != tt.attempts {
+				t.Errorf("endpoint received %d requests, want %d", n, tt.attempts)
+			}
+
+			logged := store.statuses()
+			if len(logged) != len(tt.logged) {
+				t.Fatalf("logged %v, want %v", logged, tt.logged)
+			}
+			for i := range logged {
+				if logged[i] != tt.logged[i] {
+					t.Errorf("logged %v, want %v", logged, tt.logged)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	srv, _ := webhookEndpoint(t, http.StatusServiceUnavailable)
+	w := WebhookNotifier{Client: srv.Client(), MaxAttempts: 3, Backoff: 20 * time.Millisecond}
+
+	start := time.Now()
+	if _, err := w.Deliver(models.Webhook{URL: srv.URL, Active: 1}, webhookNotification, w.MaxAttempts); err == nil {
+		t.Fatal("delivery to a failing endpoint did not fail")
+	}
+	// waits 20ms, then 40ms
+	if took := time.Since(start); took < 60*time.Millisecond {
+		t.Errorf("three attempts took %s, want the backoff to double from 20ms", took)
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	t.Run("default template", func(t *testing.T) {
+		body, err := RenderWebhookBody("", webhookNotification)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["host"] != "web1" || got["status"] != "problem" || got["old_status"] != "healthy" || got["host_service_id"] != float64(7) {
+			t.Errorf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		body, err := RenderWebhookBody(`{"text": {{json .Subject}}, "quoted": {{json .Message}}}`, webhookNotification)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]string
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["text"] != webhookNotification.Subject() || got["quoted"] != webhookNotification.Message {
+			t.Errorf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("template that is not json", func(t *testing.T) {
+		srv, received := webhookEndpoint(t, http.StatusOK)
+		store := &fakeWebhookStore{}
+		w := testWebhookNotifier(store, srv.Client())
+
+		d, err := w.Deliver(models.Webhook{URL: srv.URL, BodyTemplate: `host {{.HostName}}`, Active: 1}, webhookNotification, 1)
+		if err == nil || d.Status != "failed" {
+			t.Errorf("got %s, %v, want a failed delivery", d.Status, err)
+		}
+		if len(received) != 0 {
+			t.Error("posted a body that is not json")
+		}
+	})
+}
+
+// failingStore is a webhook store the webhooks cannot be read from
+type failingStore struct{ fakeWebhookStore }
+
+func (f *failingStore) AllWebhooks() ([]models.Webhook, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func TestWebhookEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		webhooks []models.Webhook
+		enabled  bool
+	}{
+		{"no webhooks", nil, false},
+		{"inactive", []models.Webhook{{URL: "http://hooks.example.test", Active: 0}}, false},
+		{"no url", []models.Webhook{{URL: " ", Active: 1}}, false},
+		{"active with a url", []models.Webhook{{URL: " ", Active: 1}, {URL: "http://hooks.example.test", Active: 1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWebhookNotifier(&fakeWebhookStore{webhooks: tt.webhooks})
+			if got := w.Enabled(nil); got != tt.enabled {
+				t.Errorf("Enabled = %v, want %v", got, tt.enabled)
+			}
+		})
+	}
+
+	if NewWebhookNotifier(&failingStore{}).Enabled(nil) {
+		t.Error("enabled when the webhooks could not be read")
+	}
+}
+
+func TestWebhookNotifySkipsWebhooksWithoutURL(t *testing.T) {
+	srv, received := webhookEndpoint(t, http.StatusOK)
+	store := &fakeWebhookStore{webhooks: []models.Webhook{
+		{ID: 1, Name: "blank", URL: "", Active: 1},
+		{ID: 2, Name: "ops", URL: srv.URL, Active: 1},
+		{ID: 3, Name: "off", URL: srv.URL, Active: 0},
+	}}
+	w := testWebhookNotifier(store, srv.Client())
+
+	if err := w.Notify(nil, webhookNotification); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("active webhook was not delivered to")
+	}
+	select {
+	case <-received:
+		t.Error("delivered to an inactive webhook")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the one delivery was logged, and none for the webhook without a url
+	deadline := time.Now().Add(time.Second)
+	for len(store.statuses()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, d := range store.deliveries {
+		if d.WebhookID != 2 {
+			t.Errorf("logged a delivery to webhook %d", d.WebhookID)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package oncall
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// maxMerge caps how many neighbouring pieces of time with the same user are joined into
+// one shift, so a rotation of one user does not run on forever
+const maxMerge = 100
+
+// Shift is a stretch of time one user is on call for. UserID is 0 when nobody is on
+// call, and a zero Start or End means the shift has no beginning or end.
+type Shift struct {
+	UserID   int
+	Start    time.Time
+	End      time.Time
+	Override bool
+}
+
+// At returns the shift covering t: the override on top at t if there is one, otherwise
+// the rotation shift
+func At(s models.OnCallSchedule, t time.Time) (Shift, error) {
+	loc, err := Location(s)
+	if err != nil {
+		return Shift{}, err
+	}
+
+	sh := segment(s, loc, t)
+
+	// join neighbouring pieces with the same user, e.g. a shift split by an override for
+	// the user who was on call anyway
+	for n := 0; n < maxMerge && !sh.End.IsZero(); n++ {
+		next := segment(s, loc, sh.End)
+		if next.UserID != sh.UserID {
+			break
+		}
+		sh.End = next.End
+	}
+	for n := 0; n < maxMerge && !sh.Start.IsZero(); n++ {
+		prev := segment(s, loc, sh.Start.Add(-time.Nanosecond))
+		if prev.UserID != sh.UserID {
+			break
+		}
+		sh.Start = prev.Start
+	}
+	return sh, nil
+}
+
+// Upcoming returns up to n shifts, starting with the one covering t
+func Upcoming(s models.OnCallSchedule, t time.Time, n int) ([]Shift, error) {
+	var shifts []Shift
+	for len(shifts) < n {
+		sh, err := At(s, t)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, sh)
+		if sh.End.IsZero() {
+			break
+		}
+		t = sh.End
+	}
+	return shifts, nil
+}
+
+// segment returns the piece of time around t during which nothing about the schedule
+// changes: no handoff, and no override starting or ending
+func segment(s models.OnCallSchedule, loc *time.Location, t time.Time) Shift {
+	sh := rotation(s, loc, t)
+
+	// later overrides are layered over earlier ones
+	for _, o := range s.Overrides {
+		if !o.StartsAt.After(t) && o.EndsAt.After(t) {
+			sh = Shift{UserID: o.UserID, Start: o.StartsAt, End: o.EndsAt, Override: true}
+		}
+	}
+
+	for _, o := range s.Overrides {
+		for _, b := range []time.Time{o.StartsAt, o.EndsAt} {
+			if b.After(t) && (sh.End.IsZero() || b.Before(sh.End)) {
+				sh.End = b
+			}
+			if !b.After(t) && b.After(sh.Start) {
+				sh.Start = b
+			}
+		}
+	}
+	return sh
+}
+
+// rotation returns the rotation shift covering t. Before the rotation starts, or if it
+// has nobody in it, nobody is on call.
+func rotation(s models.OnCallSchedule, loc *time.Location, t time.Time) Shift {
+	users := UserIDs(s)
+	if len(users) == 0 || s.ShiftDays < 1 {
+		return Shift{}
+	}
+
+	first := handoff(s, loc, 0)
+	if t.Before(first) {
+		return Shift{End: first}
+	}
+
+	// guess the shift from elapsed time, then correct for days that are not 24 hours long
+	k := int(t.Sub(first) / (time.Duration(s.ShiftDays) * 24 * time.Hour))
+	for !handoff(s, loc, k+1).After(t) {
+		k++
+	}
+	for k > 0 && handoff(s, loc, k).After(t) {
+		k--
+	}
+
+	return Shift{
+		UserID: users[k%len(users)],
+		Start:  handoff(s, loc, k),
+		End:    handoff(s, loc, k+1),
+	}
+}
+
+// handoff returns the time of the k-th handoff. Counting in calendar days of the
+// schedule's time zone keeps handoffs at the same local time across daylight saving
+// changes.
+func handoff(s models.OnCallSchedule, loc *time.Location, k int) time.Time {
+	start := s.RotationStart.In(loc)
+	return time.Date(start.Year(), start.Month(), start.Day()+k*s.ShiftDays, start.Hour(), start.Minute(), 0, 0, loc)
+}
+
+// Location returns the time zone of a schedule; an empty time zone means server time
+func Location(s models.OnCallSchedule) (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(s.TimeZone)
+}
+
+// Validate returns an error describing the first problem with a schedule
+func Validate(s models.OnCallSchedule) error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("please enter a name")
+	}
+	if _, err := Location(s); err != nil {
+		return errors.New("unknown time zone '" + s.TimeZone + "'")
+	}
+	if len(UserIDs(s)) == 0 {
+		return errors.New("please add at least one user to the rotation")
+	}
+	if s.ShiftDays < 1 {
+		return errors.New("shifts must be at least one day long")
+	}
+	if s.RotationStart.IsZero() {
+		return errors.New("please enter when the rotation starts")
+	}
+	return nil
+}
+
+// ValidateOverride returns an error describing the first problem with an override
+func ValidateOverride(o models.OnCallOverride) error {
+	if o.UserID == 0 {
+		return errors.New("please choose who is on call")
+	}
+	if o.StartsAt.IsZero() || o.EndsAt.IsZero() {
+		return errors.New("please enter when the override starts and ends")
+	}
+	if !o.EndsAt.After(o.StartsAt) {
+		return errors.New("the override must end after it starts")
+	}
+	return nil
+}
+
+// UserIDs returns the ids of the users in the rotation, in order
+func UserIDs(s models.OnCallSchedule) []int {
+	var ids []int
+	for _, f := range strings.Split(s.UserIDs, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(f))
+		if err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,169 @@
+package oncall
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // the tests load America/Halifax wherever they run
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// halifax leaves daylight saving time on 1 November 2026, in the middle of the second
+// week of the rotation
+var halifax, _ = time.LoadLocation("America/Halifax")
+
+// local returns a time in October or November 2026 in the schedule's time zone
+func local(month time.Month, day, hour, min int) time.Time {
+	return time.Date(2026, month, day, hour, min, 0, 0, halifax)
+}
+
+// weekly hands off between users 1, 2 and 3 every Monday at 09:00, from 19 October
+func weekly(overrides ...models.OnCallOverride) models.OnCallSchedule {
+	return models.OnCallSchedule{
+		Name:          "ops",
+		TimeZone:      "America/Halifax",
+		UserIDs:       "1,2,3",
+		ShiftDays:     7,
+		RotationStart: local(time.October, 19, 9, 0).UTC(),
+		Overrides:     overrides,
+	}
+}
+
+// checkShift fails if got is not want
+func checkShift(t *testing.T, got, want Shift) {
+	t.Helper()
+
+	if got.UserID != want.UserID || got.Override != want.Override || !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+		t.Errorf("got user %d from %s to %s (override %v), want user %d from %s to %s (override %v)",
+			got.UserID, got.Start.In(halifax), got.End.In(halifax), got.Override,
+			want.UserID, want.Start.In(halifax), want.End.In(halifax), want.Override)
+	}
+}
+
+func TestAtRotationBoundaries(t *testing.T) {
+	first := Shift{UserID: 1, Start: local(time.October, 19, 9, 0), End: local(time.October, 26, 9, 0)}
+	second := Shift{UserID: 2, Start: local(time.October, 26, 9, 0), End: local(time.November, 2, 9, 0)}
+	third := Shift{UserID: 3, Start: local(time.November, 2, 9, 0), End: local(time.November, 9, 9, 0)}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want Shift
+	}{
+		{"before the rotation starts", local(time.October, 18, 0, 0), Shift{End: first.Start}},
+		{"just before the rotation starts", first.Start.Add(-time.Nanosecond), Shift{End: first.Start}},
+		{"as the rotation starts", first.Start, first},
+		{"just before the first handoff", first.End.Add(-time.Nanosecond), first},
+		{"at the first handoff", second.Start, second},
+		{"just before a handoff after daylight saving ends", second.End.Add(-time.Nanosecond), second},
+		{"at a handoff after daylight saving ends", third.Start, third},
+		{"back to the first user", third.End, Shift{UserID: 1, Start: third.End, End: local(time.November, 16, 9, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := At(weekly(), tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkShift(t, got, tt.want)
+		})
+	}
+
+	// the week across the change is an hour longer, so the handoff stays at 09:00
+	if got := second.End.Sub(second.Start); got != 7*24*time.Hour+time.Hour {
+		t.Errorf("shift across the end of daylight saving lasts %s", got)
+	}
+}
+
+func TestAtWithOverrides(t *testing.T) {
+	// user 5 covers two days, user 6 covers a night on top of that, and user 7 covers
+	// the morning of the first handoff
+	overrides := []models.OnCallOverride{
+		{ID: 1, UserID: 5, StartsAt: local(time.October, 20, 12, 0), EndsAt: local(time.October, 22, 12, 0)},
+		{ID: 2, UserID: 6, StartsAt: local(time.October, 21, 0, 0), EndsAt: local(time.October, 21, 6, 0)},
+		{ID: 3, UserID: 7, StartsAt: local(time.October, 26, 6, 0), EndsAt: local(time.October, 26, 12, 0)},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want Shift
+	}{
+		{"rotation until the override starts", local(time.October, 20, 11, 59),
+			Shift{UserID: 1, Start: local(time.October, 19, 9, 0), End: local(time.October, 20, 12, 0)}},
+		{"as the override starts", local(time.October, 20, 12, 0),
+			Shift{UserID: 5, Start: local(time.October, 20, 12, 0), End: local(time.October, 21, 0, 0), Override: true}},
+		{"later override on top", local(time.October, 21, 0, 0),
+			Shift{UserID: 6, Start: local(time.October, 21, 0, 0), End: local(time.October, 21, 6, 0), Override: true}},
+		{"earlier override again once the later one ends", local(time.October, 21, 6, 0),
+			Shift{UserID: 5, Start: local(time.October, 21, 6, 0), End: local(time.October, 22, 12, 0), Override: true}},
+		{"rotation once the override ends", local(time.October, 22, 12, 0),
+			Shift{UserID: 1, Start: local(time.October, 22, 12, 0), End: local(time.October, 26, 6, 0)}},
+		{"override across a handoff", local(time.October, 26, 9, 0),
+			Shift{UserID: 7, Start: local(time.October, 26, 6, 0), End: local(time.October, 26, 12, 0), Override: true}},
+		{"next user once an override across a handoff ends", local(time.October, 26, 12, 0),
+			Shift{UserID: 2, Start: local(time.October, 26, 12, 0), End: local(time.November, 2, 9, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := At(weekly(overrides...), tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkShift(t, got, tt.want)
+		})
+	}
+}
+
+func TestAtJoinsOverrideForUserOnCall(t *testing.T) {
+	// user 1 is on call anyway until the handoff, and then stays on call until the 28th
+	s := weekly(models.OnCallOverride{ID: 1, UserID: 1, StartsAt: local(time.October, 20, 0, 0), EndsAt: local(time.October, 28, 0, 0)})
+
+	for _, at := range []time.Time{local(time.October, 19, 12, 0), local(time.October, 27, 0, 0)} {
+		got, err := At(s, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.UserID != 1 || !got.Start.Equal(local(time.October, 19, 9, 0)) || !got.End.Equal(local(time.October, 28, 0, 0)) {
+			t.Errorf("at %s got user %d from %s to %s, want user 1 from the 19th 09:00 to the 28th", at, got.UserID, got.Start.In(halifax), got.End.In(halifax))
+		}
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	s := weekly(
+		models.OnCallOverride{ID: 1, UserID: 5, StartsAt: local(time.October, 20, 12, 0), EndsAt: local(time.October, 22, 12, 0)},
+		models.OnCallOverride{ID: 2, UserID: 6, StartsAt: local(time.October, 21, 0, 0), EndsAt: local(time.October, 21, 6, 0)},
+	)
+
+	shifts, err := Upcoming(s, local(time.October, 19, 10, 0), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{1, 5, 6, 5, 1, 2}
+	if len(shifts) != len(want) {
+		t.Fatalf("got %d shifts, want %d", len(shifts), len(want))
+	}
+	for n, sh := range shifts {
+		if sh.UserID != want[n] {
+			t.Errorf("shift %d is user %d, want user %d", n+1, sh.UserID, want[n])
+		}
+		if n > 0 && !sh.Start.Equal(shifts[n-1].End) {
+			t.Errorf("shift %d starts at %s, want it to follow on at %s", n+1, sh.Start, shifts[n-1].End)
+		}
+	}
+}
+
+func TestAtWithoutUsers(t *testing.T) {
+	s := weekly()
+	s.UserIDs = ""
+
+	got, err := At(s, local(time.October, 20, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkShift(t, got, Shift{})
+}
@@ -0,0 +1,78 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// Summary holds availability figures for a window of time
+type Summary struct {
+	From          time.Time
+	To            time.Time
+	Observed      time.Duration // time covered by check results, less maintenance
+	Maintenance   time.Duration // time spent in maintenance windows, left out of the figures
+	Downtime      time.Duration // time spent in problem
+	Incidents     int           // number of times the service went into problem
+	Recovered     int           // incidents that ended within the window
+	TimeToRecover time.Duration // total length of recovered incidents
+}
+
+// UptimePercent returns the share of observed time that was not downtime. With
+// nothing observed it is 100.
+func (s Summary) UptimePercent() float64 {
+	if s.Observed <= 0 {
+		return 100
+	}
+	return 100 * float64(s.Observed-s.Downtime) / float64(s.Observed)
+}
+
+// MTTR returns the mean time to recover from an incident
+func (s Summary) MTTR() time.Duration {
+	if s.Recovered == 0 {
+		return 0
+	}
+	return s.TimeToRecover / time.Duration(s.Recovered)
+}
+
+// HasData reports whether any check results fell in the window
+func (s Summary) HasData() bool {
+	return s.Observed > 0
+}
+
+// FromTotals summarises the uptime totals of one host service for the window from to
+func FromTotals(t models.UptimeTotal, from, to time.Time) Summary {
+	return Summary{
+		From:          from,
+		To:            to,
+		Observed:      t.Observed,
+		Maintenance:   t.Maintenance,
+		Downtime:      t.Downtime,
+		Incidents:     t.Incidents,
+		Recovered:     t.Recovered,
+		TimeToRecover: t.TimeToRecover,
+	}
+}
+
+// Combine adds several summaries together, e.g. all services on a host
+func Combine(from, to time.Time, summaries ...Summary) Summary {
+	total := Summary{From: from, To: to}
+	for _, s := range summaries {
+		total.Observed += s.Observed
+		total.Maintenance += s.Maintenance
+		total.Downtime += s.Downtime
+		total.Incidents += s.Incidents
+		total.Recovered += s.Recovered
+		total.TimeToRecover += s.TimeToRecover
+	}
+	return total
+}
+
+// ByHostService summarises uptime totals by host service id
+func ByHostService(totals []models.UptimeTotal, from, to time.Time) map[int]Summary {
+	summaries := make(map[int]Summary)
+	for _, t := range totals {
+		summaries[t.HostServiceID] = FromTotals(t, from, to)
+	}
+	return summaries
+}
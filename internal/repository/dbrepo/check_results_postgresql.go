@@ -0,0 +1,174 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// InsertCheckResult records the outcome of a single check run
+func (m *postgresDBRepo) InsertCheckResult(cr models.CheckResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into check_results (host_service_id, host_id, status, latency_ms, message, error_class,
+			maintenance, incident_id, checked_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		cr.HostServiceID,
+		cr.HostID,
+		cr.Status,
+		cr.LatencyMS,
+		cr.Message,
+		cr.ErrorClass,
+		cr.Maintenance,
+		cr.IncidentID,
+		cr.CheckedAt,
+		time.Now(),
+		time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetCheckResultsForHostService returns the check results of a host service between
+// from and to, oldest first
+func (m *postgresDBRepo) GetCheckResultsForHostService(hostServiceID int, from, to time.Time) ([]models.CheckResult, error) {
+	query := `
+		select id, host_service_id, host_id, status, latency_ms, message, error_class, maintenance, incident_id, checked_at, created_at
+		from check_results
+		where host_service_id = $1 and checked_at >= $2 and checked_at < $3
+		order by checked_at
+	`
+	return m.queryCheckResults(query, hostServiceID, from, to)
+}
+
+// GetCheckResultsForHost returns the check results of every service on a host
+// between from and to, oldest first
+func (m *postgresDBRepo) GetCheckResultsForHost(hostID int, from, to time.Time) ([]models.CheckResult, error) {
+	query := `
+		select id, host_service_id, host_id, status, latency_ms, message, error_class, maintenance, incident_id, checked_at, created_at
+		from check_results
+		where host_id = $1 and checked_at >= $2 and checked_at < $3
+		order by checked_at
+	`
+	return m.queryCheckResults(query, hostID, from, to)
+}
+
+// GetAllCheckResults returns every check result between from and to, oldest first
+func (m *postgresDBRepo) GetAllCheckResults(from, to time.Time) ([]models.CheckResult, error) {
+	query := `
+		select id, host_service_id, host_id, status, latency_ms, message, error_class, maintenance, incident_id, checked_at, created_at
+		from check_results
+		where checked_at >= $1 and checked_at < $2
+		order by checked_at
+	`
+	return m.queryCheckResults(query, from, to)
+}
+
+// CountStatusChanges returns how many times the check results of a host service changed
+// status between from and to
+func (m *postgresDBRepo) CountStatusChanges(hostServiceID int, from, to time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select count(*)
+		from (
+			select status, lag(status) over (order by checked_at) as previous
+			from check_results
+			where host_service_id = $1 and checked_at >= $2 and checked_at < $3
+		) r
+		where previous is not null and status <> previous
+	`
+
+	var n int
+	err := m.DB.QueryRowContext(ctx, query, hostServiceID, from, to).Scan(&n)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// pruneBatchSize is how many check results are deleted by one statement
+const pruneBatchSize = 10000
+
+// DeleteCheckResultsBefore deletes the check results taken before t, a batch at a time
+// so that no one statement runs for long, and returns how many were deleted
+func (m *postgresDBRepo) DeleteCheckResultsBefore(t time.Time) (int64, error) {
+	query := `
+		delete from check_results
+		where id in (select id from check_results where checked_at < $1 limit $2)
+	`
+
+	var total int64
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		res, err := m.DB.ExecContext(ctx, query, t, pruneBatchSize)
+		cancel()
+		if err != nil {
+			log.Println(err)
+			return total, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			log.Println(err)
+			return total, err
+		}
+		total += n
+		if n < pruneBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// queryCheckResults runs a check_results query and scans the rows
+func (m *postgresDBRepo) queryCheckResults(query string, args ...interface{}) ([]models.CheckResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.CheckResult
+	for rows.Next() {
+		var cr models.CheckResult
+		err := rows.Scan(
+			&cr.ID,
+			&cr.HostServiceID,
+			&cr.HostID,
+			&cr.Status,
+			&cr.LatencyMS,
+			&cr.Message,
+			&cr.ErrorClass,
+			&cr.Maintenance,
+			&cr.IncidentID,
+			&cr.CheckedAt,
+			&cr.CreatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		results = append(results, cr)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return results, nil
+}
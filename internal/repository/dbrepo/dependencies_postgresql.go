@@ -0,0 +1,151 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// hostDependencySelect selects dependencies along with the names of the hosts and
+// services involved
+const hostDependencySelect = `
+	select d.id, d.host_id, d.host_service_id, d.parent_host_id, d.parent_host_service_id,
+		h.host_name, coalesce(s.service_name, ''), ph.host_name, coalesce(ps.service_name, ''),
+		d.created_at, d.updated_at
+	from host_dependencies d
+		left join hosts h on (h.id = d.host_id)
+		left join host_services hs on (hs.id = d.host_service_id)
+		left join services s on (s.id = hs.service_id)
+		left join hosts ph on (ph.id = d.parent_host_id)
+		left join host_services phs on (phs.id = d.parent_host_service_id)
+		left join services ps on (ps.id = phs.service_id)
+`
+
+// AllHostDependencies returns every host dependency
+func (m *postgresDBRepo) AllHostDependencies() ([]models.HostDependency, error) {
+	return m.queryHostDependencies(hostDependencySelect + ` order by h.host_name, ph.host_name`)
+}
+
+// GetDependenciesForHost returns the parents a host and its services depend on
+func (m *postgresDBRepo) GetDependenciesForHost(hostID int) ([]models.HostDependency, error) {
+	return m.queryHostDependencies(hostDependencySelect+` where d.host_id = $1 order by ph.host_name`, hostID)
+}
+
+// queryHostDependencies runs a host dependency query and scans the rows
+func (m *postgresDBRepo) queryHostDependencies(query string, args ...interface{}) ([]models.HostDependency, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []models.HostDependency
+	for rows.Next() {
+		var d models.HostDependency
+		err := rows.Scan(
+			&d.ID,
+			&d.HostID,
+			&d.HostServiceID,
+			&d.ParentHostID,
+			&d.ParentHostServiceID,
+			&d.HostName,
+			&d.ServiceName,
+			&d.ParentHostName,
+			&d.ParentServiceName,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return deps, nil
+}
+
+// InsertHostDependency inserts a host dependency and returns its id
+func (m *postgresDBRepo) InsertHostDependency(d models.HostDependency) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into host_dependencies (host_id, host_service_id, parent_host_id, parent_host_service_id,
+			created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt,
+		d.HostID,
+		d.HostServiceID,
+		d.ParentHostID,
+		d.ParentHostServiceID,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return newID, err
+	}
+	return newID, nil
+}
+
+// DeleteHostDependency deletes a host dependency
+func (m *postgresDBRepo) DeleteHostDependency(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from host_dependencies where id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// GetDownParent returns a description of the first parent of a host service that is in
+// problem or itself unreachable, or an empty string if all of its parents are up
+func (m *postgresDBRepo) GetDownParent(hostID, hostServiceID int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select s.service_name, ph.host_name, phs.status
+		from host_dependencies d
+			join hosts ph on (ph.id = d.parent_host_id)
+			join host_services phs on (phs.host_id = d.parent_host_id)
+			join services s on (s.id = phs.service_id)
+		where d.host_id = $1
+			and (d.host_service_id = 0 or d.host_service_id = $2)
+			and (d.parent_host_service_id = 0 or d.parent_host_service_id = phs.id)
+			and phs.active = 1
+			and phs.status in ('problem', 'unreachable')
+		order by phs.status, ph.host_name
+		limit 1
+	`
+
+	var serviceName, hostName, status string
+	err := m.DB.QueryRowContext(ctx, query, hostID, hostServiceID).Scan(&serviceName, &hostName, &status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	return fmt.Sprintf("%s on %s is %s", serviceName, hostName, status), nil
+}
@@ -0,0 +1,239 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// AllEscalationPolicies returns all escalation policies with their steps, ordered by name
+func (m *postgresDBRepo) AllEscalationPolicies() ([]models.EscalationPolicy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, repeat_minutes, active, created_at, updated_at
+		from escalation_policies
+		order by name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []models.EscalationPolicy
+	for rows.Next() {
+		var p models.EscalationPolicy
+		err := rows.Scan(
+			&p.ID,
+			&p.Name,
+			&p.RepeatMinutes,
+			&p.Active,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	for i := range policies {
+		policies[i].Steps, err = m.getEscalationSteps(policies[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return policies, nil
+}
+
+// GetEscalationPolicyByID returns an escalation policy with its steps
+func (m *postgresDBRepo) GetEscalationPolicyByID(id int) (models.EscalationPolicy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, repeat_minutes, active, created_at, updated_at
+		from escalation_policies
+		where id = $1
+	`
+
+	var p models.EscalationPolicy
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&p.ID,
+		&p.Name,
+		&p.RepeatMinutes,
+		&p.Active,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		return p, err
+	}
+
+	p.Steps, err = m.getEscalationSteps(p.ID)
+	if err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// getEscalationSteps returns the steps of an escalation policy in order
+func (m *postgresDBRepo) getEscalationSteps(policyID int) ([]models.EscalationStep, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, escalation_policy_id, position, delay_minutes, user_ids, channels, created_at, updated_at
+		from escalation_steps
+		where escalation_policy_id = $1
+		order by position, delay_minutes
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, policyID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var steps []models.EscalationStep
+	for rows.Next() {
+		var s models.EscalationStep
+		err := rows.Scan(
+			&s.ID,
+			&s.EscalationPolicyID,
+			&s.Position,
+			&s.DelayMinutes,
+			&s.UserIDs,
+			&s.Channels,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return steps, nil
+}
+
+// InsertEscalationPolicy inserts an escalation policy and its steps, and returns its id
+func (m *postgresDBRepo) InsertEscalationPolicy(p models.EscalationPolicy) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into escalation_policies (name, repeat_minutes, active, created_at, updated_at)
+		values ($1, $2, $3, $4, $5) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt, p.Name, p.RepeatMinutes, p.Active, time.Now(), time.Now()).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return newID, err
+	}
+
+	err = m.replaceEscalationSteps(newID, p.Steps)
+	if err != nil {
+		return newID, err
+	}
+	return newID, nil
+}
+
+// UpdateEscalationPolicy updates an escalation policy and replaces its steps
+func (m *postgresDBRepo) UpdateEscalationPolicy(p models.EscalationPolicy) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update escalation_policies set name = $1, repeat_minutes = $2, active = $3, updated_at = $4
+		where id = $5
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt, p.Name, p.RepeatMinutes, p.Active, time.Now(), p.ID)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return m.replaceEscalationSteps(p.ID, p.Steps)
+}
+
+// replaceEscalationSteps swaps the steps of a policy for new ones in one transaction
+func (m *postgresDBRepo) replaceEscalationSteps(policyID int, steps []models.EscalationStep) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from escalation_steps where escalation_policy_id = $1`, policyID)
+	if err != nil {
+		log.Println(err)
+		tx.Rollback()
+		return err
+	}
+
+	stmt := `
+		insert into escalation_steps (escalation_policy_id, position, delay_minutes, user_ids, channels,
+			created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7)
+	`
+	for i, s := range steps {
+		_, err = tx.ExecContext(ctx, stmt, policyID, i+1, s.DelayMinutes, s.UserIDs, s.Channels, time.Now(), time.Now())
+		if err != nil {
+			log.Println(err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteEscalationPolicy deletes an escalation policy and detaches it from hosts
+func (m *postgresDBRepo) DeleteEscalationPolicy(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `update hosts set escalation_policy_id = 0 where escalation_policy_id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, `delete from escalation_policies where id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
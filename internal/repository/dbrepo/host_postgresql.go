@@ -13,8 +13,9 @@ func (m *postgresDBRepo) InsertHost(h models.Host) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel() // NTS - only cancel when function finishes (in the case the above is successful and ctx has value). Otherwise, cancel() will run anyway.
 
-	query := `insert into hosts (host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at)
-		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning id`
+	query := `insert into hosts (host_name, canonical_name, url, ip, ipv6, location, os, active, pagerduty_routing_key,
+		escalation_policy_id, oncall_schedule_id, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) returning id`
 
 	var newID int
 
@@ -28,6 +29,9 @@ func (m *postgresDBRepo) InsertHost(h models.Host) (int, error) {
 		h.Location,
 		h.OS,
 		h.Active,
+		h.PagerDutyRoutingKey,
+		h.EscalationPolicyID,
+		h.OnCallScheduleID,
 		time.Now(),
 		time.Now(),
 	).Scan(&newID)
@@ -71,7 +75,8 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 	defer cancel()
 
 	query := `
-		select id, host_name, canonical_name, url, ip, ipv6, location, os, active, created_at, updated_at
+		select id, host_name, canonical_name, url, ip, ipv6, location, os, active, pagerduty_routing_key,
+			escalation_policy_id, oncall_schedule_id, created_at, updated_at
 		from hosts where id = $1
 	`
 
@@ -90,6 +95,9 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 		&h.Location,
 		&h.OS,
 		&h.Active,
+		&h.PagerDutyRoutingKey,
+		&h.EscalationPolicyID,
+		&h.OnCallScheduleID,
 		&h.CreatedAt,
 		&h.UpdatedAt,
 	)
@@ -100,9 +108,16 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 	// get all services for host
 	query = `
 		select 
-			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
+			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit,
+			hs.schedule_cron, hs.schedule_time_zone,
 			hs.last_check, hs.status, hs.created_at, hs.updated_at,
-			s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, hs.last_message
+			s.id, s.service_name, s.service_type, s.active, s.icon, s.created_at, s.updated_at, hs.last_message,
+			hs.port, hs.dns_resolver, hs.dns_record_type, hs.dns_expected,
+			hs.http_path, hs.http_method, hs.http_headers, hs.http_body, hs.expected_status,
+			hs.body_match, hs.body_match_regex, hs.body_match_negate,
+			hs.warn_latency_ms, hs.critical_latency_ms,
+			hs.fail_after, hs.recover_after, hs.recheck_seconds,
+			hs.confirm_status, hs.confirm_count, hs.flapping
 		from 
 			host_services hs 
 			left join services s on (s.id = hs.service_id)
@@ -129,17 +144,40 @@ func (m *postgresDBRepo) GetHostByID(id int) (models.Host, error) {
 			&hs.Active,
 			&hs.ScheduleNumber,
 			&hs.ScheduleUnit,
+			&hs.ScheduleCron,
+			&hs.ScheduleTimeZone,
 			&hs.LastCheck,
 			&hs.Status,
 			&hs.CreatedAt,
 			&hs.UpdatedAt,
 			&hs.Service.ID,
 			&hs.Service.ServiceName,
+			&hs.Service.ServiceType,
 			&hs.Service.Active,
 			&hs.Service.Icon,
 			&hs.Service.CreatedAt,
 			&hs.Service.UpdatedAt,
 			&hs.LastMessage,
+			&hs.Config.Port,
+			&hs.Config.DNSResolver,
+			&hs.Config.DNSRecordType,
+			&hs.Config.DNSExpected,
+			&hs.Config.HTTPPath,
+			&hs.Config.HTTPMethod,
+			&hs.Config.HTTPHeaders,
+			&hs.Config.HTTPBody,
+			&hs.Config.ExpectedStatus,
+			&hs.Config.BodyMatch,
+			&hs.Config.BodyMatchRegex,
+			&hs.Config.BodyMatchNegate,
+			&hs.Config.WarnLatency,
+			&hs.Config.CriticalLatency,
+			&hs.Config.FailAfter,
+			&hs.Config.RecoverAfter,
+			&hs.Config.RecheckSeconds,
+			&hs.ConfirmStatus,
+			&hs.ConfirmCount,
+			&hs.Flapping,
 		)
 		if err != nil {
 			log.Println(err)
@@ -158,7 +196,8 @@ func (m *postgresDBRepo) UpdateHost(h models.Host) error {
 
 	stmt := `
 		update hosts set host_name = $1, canonical_name = $2, url = $3, ip = $4, ipv6 = $5, 
-		os = $6, location = $7, active = $8, updated_at = $9 where id = $10
+		location = $6, os = $7, active = $8, pagerduty_routing_key = $9, escalation_policy_id = $10,
+		oncall_schedule_id = $11, updated_at = $12 where id = $13
 		
 		`
 	_, err := m.DB.ExecContext(ctx, stmt,
@@ -170,6 +209,9 @@ func (m *postgresDBRepo) UpdateHost(h models.Host) error {
 		h.Location,
 		h.OS,
 		h.Active,
+		h.PagerDutyRoutingKey,
+		h.EscalationPolicyID,
+		h.OnCallScheduleID,
 		time.Now(),
 		h.ID,
 	)
@@ -249,9 +291,10 @@ func (m *postgresDBRepo) AllHosts() ([]models.Host, error) {
 
 		serviceQuery := `
 		select 
-			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
+			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit,
+			hs.schedule_cron, hs.schedule_time_zone,
 			hs.last_check, hs.status, hs.created_at, hs.updated_at,
-			s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, hs.last_message
+			s.id, s.service_name, s.service_type, s.active, s.icon, s.created_at, s.updated_at, hs.last_message
 		from 
 			host_services hs 
 			left join services s on (s.id = hs.service_id)
@@ -273,12 +316,15 @@ func (m *postgresDBRepo) AllHosts() ([]models.Host, error) {
 				&hs.Active,
 				&hs.ScheduleNumber,
 				&hs.ScheduleUnit,
+				&hs.ScheduleCron,
+				&hs.ScheduleTimeZone,
 				&hs.LastCheck,
 				&hs.Status,
 				&hs.CreatedAt,
 				&hs.UpdatedAt,
 				&hs.Service.ID,
 				&hs.Service.ServiceName,
+				&hs.Service.ServiceType,
 				&hs.Service.Active,
 				&hs.Service.Icon,
 				&hs.Service.CreatedAt,
@@ -290,8 +336,8 @@ func (m *postgresDBRepo) AllHosts() ([]models.Host, error) {
 				return nil, err
 			}
 			hostServices = append(hostServices, hs)
-			serviceRows.Close() // nts - not defer before a for loop
 		}
+		serviceRows.Close() // nts - not defer inside a for loop, and not inside the rows loop either
 		h.HostServices = hostServices
 		hosts = append(hosts, h)
 	}
@@ -325,7 +371,8 @@ func (m *postgresDBRepo) GetServicesByStatus(status string) ([]models.HostServic
 	defer cancel()
 	query := `
 		select 
-			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
+			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit,
+			hs.schedule_cron, hs.schedule_time_zone,
 			hs.last_check, hs.status, hs.created_at, hs.updated_at,
 			h.host_name, s.service_name, s.icon, hs.last_message
 		from 
@@ -354,6 +401,8 @@ func (m *postgresDBRepo) GetServicesByStatus(status string) ([]models.HostServic
 			&hs.Active,
 			&hs.ScheduleNumber,
 			&hs.ScheduleUnit,
+			&hs.ScheduleCron,
+			&hs.ScheduleTimeZone,
 			&hs.LastCheck,
 			&hs.Status,
 			&hs.CreatedAt,
@@ -379,9 +428,16 @@ func (m *postgresDBRepo) GetHostServiceByID(id int) (models.HostService, error)
 	// nts - I had got the id criteria wrong!! careful when copy and paste.
 	query := `
 		select 
-			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
+			hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit,
+			hs.schedule_cron, hs.schedule_time_zone,
 			hs.last_check, hs.status, hs.created_at, hs.updated_at,
-			s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, h.host_name, hs.last_message
+			s.id, s.service_name, s.service_type, s.active, s.icon, s.created_at, s.updated_at, h.host_name, hs.last_message,
+			hs.port, hs.dns_resolver, hs.dns_record_type, hs.dns_expected,
+			hs.http_path, hs.http_method, hs.http_headers, hs.http_body, hs.expected_status,
+			hs.body_match, hs.body_match_regex, hs.body_match_negate,
+			hs.warn_latency_ms, hs.critical_latency_ms,
+			hs.fail_after, hs.recover_after, hs.recheck_seconds,
+			hs.confirm_status, hs.confirm_count, hs.flapping
 		from 
 			host_services hs 
 			left join services s on (s.id = hs.service_id)
@@ -401,18 +457,41 @@ func (m *postgresDBRepo) GetHostServiceByID(id int) (models.HostService, error)
 		&hs.Active,
 		&hs.ScheduleNumber,
 		&hs.ScheduleUnit,
+		&hs.ScheduleCron,
+		&hs.ScheduleTimeZone,
 		&hs.LastCheck,
 		&hs.Status,
 		&hs.CreatedAt,
 		&hs.UpdatedAt,
 		&hs.Service.ID,
 		&hs.Service.ServiceName,
+		&hs.Service.ServiceType,
 		&hs.Service.Active,
 		&hs.Service.Icon,
 		&hs.Service.CreatedAt,
 		&hs.Service.UpdatedAt,
 		&hs.HostName,
 		&hs.LastMessage,
+		&hs.Config.Port,
+		&hs.Config.DNSResolver,
+		&hs.Config.DNSRecordType,
+		&hs.Config.DNSExpected,
+		&hs.Config.HTTPPath,
+		&hs.Config.HTTPMethod,
+		&hs.Config.HTTPHeaders,
+		&hs.Config.HTTPBody,
+		&hs.Config.ExpectedStatus,
+		&hs.Config.BodyMatch,
+		&hs.Config.BodyMatchRegex,
+		&hs.Config.BodyMatchNegate,
+		&hs.Config.WarnLatency,
+		&hs.Config.CriticalLatency,
+		&hs.Config.FailAfter,
+		&hs.Config.RecoverAfter,
+		&hs.Config.RecheckSeconds,
+		&hs.ConfirmStatus,
+		&hs.ConfirmCount,
+		&hs.Flapping,
 	)
 	if err != nil {
 		log.Println(err)
@@ -454,21 +533,94 @@ func (m *postgresDBRepo) UpdateHostService(hs models.HostService) error {
 	return nil
 }
 
+// UpdateHostServiceConfig updates the check settings and schedule of a host service
+func (m *postgresDBRepo) UpdateHostServiceConfig(hs models.HostService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update host_services set 
+			port = $1, dns_resolver = $2, dns_record_type = $3, dns_expected = $4, 
+			http_path = $5, http_method = $6, http_headers = $7, http_body = $8,
+			expected_status = $9, body_match = $10, body_match_regex = $11, body_match_negate = $12,
+			warn_latency_ms = $13, critical_latency_ms = $14,
+			fail_after = $15, recover_after = $16, recheck_seconds = $17,
+			schedule_number = $18, schedule_unit = $19, schedule_cron = $20, schedule_time_zone = $21,
+			updated_at = $22 
+		where 
+			id = $23
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		hs.Config.Port,
+		hs.Config.DNSResolver,
+		hs.Config.DNSRecordType,
+		hs.Config.DNSExpected,
+		hs.Config.HTTPPath,
+		hs.Config.HTTPMethod,
+		hs.Config.HTTPHeaders,
+		hs.Config.HTTPBody,
+		hs.Config.ExpectedStatus,
+		hs.Config.BodyMatch,
+		hs.Config.BodyMatchRegex,
+		hs.Config.BodyMatchNegate,
+		hs.Config.WarnLatency,
+		hs.Config.CriticalLatency,
+		hs.Config.FailAfter,
+		hs.Config.RecoverAfter,
+		hs.Config.RecheckSeconds,
+		hs.ScheduleNumber,
+		hs.ScheduleUnit,
+		hs.ScheduleCron,
+		hs.ScheduleTimeZone,
+		time.Now(),
+		hs.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateHostServiceCheckState saves the confirmation and flapping state of a host service
+func (m *postgresDBRepo) UpdateHostServiceCheckState(hs models.HostService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update host_services set confirm_status = $1, confirm_count = $2, flapping = $3, updated_at = $4
+		where id = $5
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		hs.ConfirmStatus,
+		hs.ConfirmCount,
+		hs.Flapping,
+		time.Now(),
+		hs.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (m *postgresDBRepo) GetServicesToMonitor() ([]models.HostService, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	query :=
 		`select 
-		hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
+		hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit,
+		hs.schedule_cron, hs.schedule_time_zone,
 		hs.last_check, hs.status, hs.created_at, hs.updated_at,
-		s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at,
+		s.id, s.service_name, s.service_type, s.active, s.icon, s.created_at, s.updated_at,
 		h.host_name, hs.last_message
 	from 
 		host_services hs 
 		left join services s on (s.id = hs.service_id)
 		left join hosts h on (hs.host_id = h.id)
 	where 
-		h.active = 1 and hs.active = 1
+		h.active = 1 and hs.active = 1 and s.active = 1
 	`
 
 	var services []models.HostService
@@ -488,12 +640,15 @@ func (m *postgresDBRepo) GetServicesToMonitor() ([]models.HostService, error) {
 			&hs.Active,
 			&hs.ScheduleNumber,
 			&hs.ScheduleUnit,
+			&hs.ScheduleCron,
+			&hs.ScheduleTimeZone,
 			&hs.LastCheck,
 			&hs.Status,
 			&hs.CreatedAt,
 			&hs.UpdatedAt,
 			&hs.Service.ID,
 			&hs.Service.ServiceName,
+			&hs.Service.ServiceType,
 			&hs.Service.Active,
 			&hs.Service.Icon,
 			&hs.Service.CreatedAt,
@@ -516,9 +671,10 @@ func (m *postgresDBRepo) GetHostServiceByHostIDServiceID(hostID, serviceID int)
 	defer cancel()
 	query :=
 		`select 
-		hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit, 
+		hs.id, hs.host_id, hs.service_id, hs.active, hs.schedule_number, hs.schedule_unit,
+		hs.schedule_cron, hs.schedule_time_zone,
 		hs.last_check, hs.status, hs.created_at, hs.updated_at,
-		s.id, s.service_name, s.active, s.icon, s.created_at, s.updated_at, 
+		s.id, s.service_name, s.service_type, s.active, s.icon, s.created_at, s.updated_at, 
 		h.host_name, hs.last_message
 	from 
 		host_services hs 
@@ -538,12 +694,15 @@ func (m *postgresDBRepo) GetHostServiceByHostIDServiceID(hostID, serviceID int)
 		&hs.Active,
 		&hs.ScheduleNumber,
 		&hs.ScheduleUnit,
+		&hs.ScheduleCron,
+		&hs.ScheduleTimeZone,
 		&hs.LastCheck,
 		&hs.Status,
 		&hs.CreatedAt,
 		&hs.UpdatedAt,
 		&hs.Service.ID,
 		&hs.Service.ServiceName,
+		&hs.Service.ServiceType,
 		&hs.Service.Active,
 		&hs.Service.Icon,
 		&hs.Service.CreatedAt,
@@ -563,8 +722,8 @@ func (m *postgresDBRepo) InsertEvent(e models.Event) error {
 	defer cancel()
 	stmt := `
 		insert into events (host_service_id, event_type, host_id, service_name, host_name,
-		message, created_at, updated_at)
-		values($1, $2, $3, $4, $5, $6, $7, $8)
+		message, maintenance, incident_id, created_at, updated_at)
+		values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 	_, err := m.DB.ExecContext(ctx, stmt,
 		e.HostServiceID,
@@ -573,6 +732,8 @@ func (m *postgresDBRepo) InsertEvent(e models.Event) error {
 		e.ServiceName,
 		e.HostName,
 		e.Message,
+		e.Maintenance,
+		e.IncidentID,
 		time.Now(),
 		time.Now(),
 	)
@@ -589,7 +750,7 @@ func (m *postgresDBRepo) GetAllEvents() ([]models.Event, error) {
 	defer cancel()
 	stmt := `
 		select id, host_service_id, event_type, host_id, service_name, host_name,
-			message, created_at, updated_at
+			message, maintenance, incident_id, created_at, updated_at
 		from events 
 		order by created_at
 	`
@@ -611,6 +772,8 @@ func (m *postgresDBRepo) GetAllEvents() ([]models.Event, error) {
 			&event.ServiceName,
 			&event.HostName,
 			&event.Message,
+			&event.Maintenance,
+			&event.IncidentID,
 			&event.CreatedAt,
 			&event.UpdatedAt,
 		)
@@ -0,0 +1,401 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// incidentSelect selects incidents along with the names of the host, service and the
+// user who acknowledged them
+const incidentSelect = `
+	select i.id, i.host_id, i.host_service_id, i.title, i.status, i.opened_at, i.acknowledged_at,
+		i.acknowledged_by, coalesce(u.first_name || ' ' || u.last_name, ''), i.resolved_at, i.resolution,
+		i.escalation_step, i.last_escalated_at, coalesce(h.host_name, ''), coalesce(s.service_name, ''),
+		i.created_at, i.updated_at
+	from incidents i
+		left join users u on (u.id = i.acknowledged_by)
+		left join hosts h on (h.id = i.host_id)
+		left join host_services hs on (hs.id = i.host_service_id)
+		left join services s on (s.id = hs.service_id)
+`
+
+// AllIncidents returns incidents newest first, only those with status if it is not empty
+func (m *postgresDBRepo) AllIncidents(status string) ([]models.Incident, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := incidentSelect + `
+		where $1 = '' or i.status = $1
+		order by i.opened_at desc
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, status)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []models.Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return incidents, nil
+}
+
+// GetIncidentByID returns an incident by id
+func (m *postgresDBRepo) GetIncidentByID(id int) (models.Incident, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	i, err := scanIncident(m.DB.QueryRowContext(ctx, incidentSelect+` where i.id = $1`, id))
+	if err != nil {
+		log.Println(err)
+		return i, err
+	}
+	return i, nil
+}
+
+// GetOpenIncidentForHostService returns the unresolved incident of a host service. If
+// there is none, the incident returned has an ID of 0.
+func (m *postgresDBRepo) GetOpenIncidentForHostService(hostServiceID int) (models.Incident, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := incidentSelect + `
+		where i.host_service_id = $1 and i.status <> 'resolved'
+		order by i.opened_at desc
+		limit 1
+	`
+
+	i, err := scanIncident(m.DB.QueryRowContext(ctx, query, hostServiceID))
+	if err == sql.ErrNoRows {
+		return models.Incident{}, nil
+	}
+	if err != nil {
+		log.Println(err)
+		return i, err
+	}
+	return i, nil
+}
+
+// InsertIncident opens an incident and returns its id
+func (m *postgresDBRepo) InsertIncident(i models.Incident) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into incidents (host_id, host_service_id, title, status, opened_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt,
+		i.HostID,
+		i.HostServiceID,
+		i.Title,
+		i.Status,
+		i.OpenedAt,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return newID, err
+	}
+	return newID, nil
+}
+
+// AcknowledgeIncident marks an open incident as acknowledged by a user. It reports
+// false if there is no open incident with that id.
+func (m *postgresDBRepo) AcknowledgeIncident(id, userID int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update incidents set status = 'acknowledged', acknowledged_at = $1, acknowledged_by = $2, updated_at = $3
+		where id = $4 and status = 'open'
+	`
+
+	res, err := m.DB.ExecContext(ctx, stmt, time.Now(), userID, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ResolveIncident marks an incident as resolved, if it is not already
+func (m *postgresDBRepo) ResolveIncident(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update incidents set status = 'resolved', resolved_at = $1, updated_at = $2
+		where id = $3 and status <> 'resolved'
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt, time.Now(), time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// UpdateIncidentResolution saves the resolution summary of an incident
+func (m *postgresDBRepo) UpdateIncidentResolution(id int, resolution string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `update incidents set resolution = $1, updated_at = $2 where id = $3`
+
+	_, err := m.DB.ExecContext(ctx, stmt, resolution, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// InsertIncidentNote adds a note to an incident and returns its id
+func (m *postgresDBRepo) InsertIncidentNote(n models.IncidentNote) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into incident_notes (incident_id, user_id, note, created_at, updated_at)
+		values ($1, $2, $3, $4, $5) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt, n.IncidentID, n.UserID, n.Note, time.Now(), time.Now()).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return newID, err
+	}
+	return newID, nil
+}
+
+// GetIncidentNotes returns the notes of an incident, oldest first
+func (m *postgresDBRepo) GetIncidentNotes(incidentID int) ([]models.IncidentNote, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select n.id, n.incident_id, n.user_id, coalesce(u.first_name || ' ' || u.last_name, ''), n.note,
+			n.created_at, n.updated_at
+		from incident_notes n
+			left join users u on (u.id = n.user_id)
+		where n.incident_id = $1
+		order by n.created_at
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, incidentID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.IncidentNote
+	for rows.Next() {
+		var n models.IncidentNote
+		err := rows.Scan(
+			&n.ID,
+			&n.IncidentID,
+			&n.UserID,
+			&n.UserName,
+			&n.Note,
+			&n.CreatedAt,
+			&n.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return notes, nil
+}
+
+// GetEventsForIncident returns the events of an incident, oldest first
+func (m *postgresDBRepo) GetEventsForIncident(incidentID int) ([]models.Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, host_service_id, event_type, host_id, service_name, host_name,
+			message, maintenance, incident_id, created_at, updated_at
+		from events
+		where incident_id = $1
+		order by created_at
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, incidentID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		err := rows.Scan(
+			&e.ID,
+			&e.HostServiceID,
+			&e.EventType,
+			&e.HostID,
+			&e.ServiceName,
+			&e.HostName,
+			&e.Message,
+			&e.Maintenance,
+			&e.IncidentID,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetCheckResultsForIncident returns the check results of an incident, oldest first
+func (m *postgresDBRepo) GetCheckResultsForIncident(incidentID int) ([]models.CheckResult, error) {
+	query := `
+		select id, host_service_id, host_id, status, latency_ms, message, error_class, maintenance, incident_id, checked_at, created_at
+		from check_results
+		where incident_id = $1
+		order by checked_at
+	`
+	return m.queryCheckResults(query, incidentID)
+}
+
+// LinkCheckResultsToIncident links the check results of a host service from from
+// onwards that do not belong to an incident yet
+func (m *postgresDBRepo) LinkCheckResultsToIncident(incidentID, hostServiceID int, from time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update check_results set incident_id = $1
+		where host_service_id = $2 and checked_at >= $3 and incident_id = 0
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt, incidentID, hostServiceID, from)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// GetIncidentsByStatus returns the incidents with a status, oldest first
+func (m *postgresDBRepo) GetIncidentsByStatus(status string) ([]models.Incident, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, incidentSelect+` where i.status = $1 order by i.opened_at`, status)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []models.Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return incidents, nil
+}
+
+// UpdateIncidentEscalation saves how far an incident has been escalated and when the
+// last escalation or reminder was sent
+func (m *postgresDBRepo) UpdateIncidentEscalation(id, step int, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `update incidents set escalation_step = $1, last_escalated_at = $2, updated_at = $3 where id = $4`
+
+	_, err := m.DB.ExecContext(ctx, stmt, step, at, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// scanIncident scans an incidents row; null acknowledged and resolved times become
+// zero times
+func scanIncident(row rowScanner) (models.Incident, error) {
+	var i models.Incident
+	var acknowledgedAt, resolvedAt, lastEscalatedAt sql.NullTime
+
+	err := row.Scan(
+		&i.ID,
+		&i.HostID,
+		&i.HostServiceID,
+		&i.Title,
+		&i.Status,
+		&i.OpenedAt,
+		&acknowledgedAt,
+		&i.AcknowledgedBy,
+		&i.AcknowledgedByName,
+		&resolvedAt,
+		&i.Resolution,
+		&i.EscalationStep,
+		&lastEscalatedAt,
+		&i.HostName,
+		&i.ServiceName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	i.AcknowledgedAt = acknowledgedAt.Time
+	i.ResolvedAt = resolvedAt.Time
+	i.LastEscalatedAt = lastEscalatedAt.Time
+	return i, err
+}
@@ -0,0 +1,64 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// AcquireLease takes or renews a lease for holder, for ttl from now, if it is free,
+// expired or already held by holder. It returns the lease as it is afterwards, so the
+// holder is someone else if it could not be taken. Times are the database's, so the
+// clocks of the instances do not need to agree.
+func (m *postgresDBRepo) AcquireLease(name, holder string, ttl time.Duration) (models.Lease, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into leases (name, holder, expires_at, created_at, updated_at)
+		values ($1, $2, now() + make_interval(secs => $3), now(), now())
+		on conflict (name) do update
+			set holder = excluded.holder, expires_at = excluded.expires_at, updated_at = excluded.updated_at
+			where leases.holder = excluded.holder or leases.expires_at < now()
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt, name, holder, ttl.Seconds())
+	if err != nil {
+		log.Println(err)
+		return models.Lease{}, err
+	}
+
+	query := `select name, holder, expires_at, created_at, updated_at from leases where name = $1`
+
+	var l models.Lease
+	err = m.DB.QueryRowContext(ctx, query, name).Scan(
+		&l.Name,
+		&l.Holder,
+		&l.ExpiresAt,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		return l, err
+	}
+	return l, nil
+}
+
+// ReleaseLease lets a lease expire now, if holder holds it, so another instance can
+// take it straight away
+func (m *postgresDBRepo) ReleaseLease(name, holder string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `update leases set expires_at = now(), updated_at = now() where name = $1 and holder = $2`
+
+	_, err := m.DB.ExecContext(ctx, stmt, name, holder)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package dbrepo
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// AllMaintenanceWindows returns all maintenance windows, ordered by name
+func (m *postgresDBRepo) AllMaintenanceWindows() ([]models.MaintenanceWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, scope, host_id, host_service_id, starts_at, ends_at, cron_expression,
+			duration_minutes, active, created_at, updated_at
+		from maintenance_windows
+		order by name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []models.MaintenanceWindow
+	for rows.Next() {
+		mw, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		windows = append(windows, mw)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return windows, nil
+}
+
+// GetMaintenanceWindowByID returns a maintenance window by id
+func (m *postgresDBRepo) GetMaintenanceWindowByID(id int) (models.MaintenanceWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, scope, host_id, host_service_id, starts_at, ends_at, cron_expression,
+			duration_minutes, active, created_at, updated_at
+		from maintenance_windows
+		where id = $1
+	`
+
+	mw, err := scanMaintenanceWindow(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		log.Println(err)
+		return mw, err
+	}
+	return mw, nil
+}
+
+// InsertMaintenanceWindow inserts a maintenance window and returns its id
+func (m *postgresDBRepo) InsertMaintenanceWindow(mw models.MaintenanceWindow) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into maintenance_windows (name, scope, host_id, host_service_id, starts_at, ends_at,
+			cron_expression, duration_minutes, active, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt,
+		mw.Name,
+		mw.Scope,
+		mw.HostID,
+		mw.HostServiceID,
+		nullTime(mw.StartsAt),
+		nullTime(mw.EndsAt),
+		mw.CronExpression,
+		mw.DurationMinutes,
+		mw.Active,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+	return newID, nil
+}
+
+// UpdateMaintenanceWindow updates a maintenance window by id
+func (m *postgresDBRepo) UpdateMaintenanceWindow(mw models.MaintenanceWindow) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update maintenance_windows set name = $1, scope = $2, host_id = $3, host_service_id = $4,
+			starts_at = $5, ends_at = $6, cron_expression = $7, duration_minutes = $8, active = $9,
+			updated_at = $10
+		where id = $11
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		mw.Name,
+		mw.Scope,
+		mw.HostID,
+		mw.HostServiceID,
+		nullTime(mw.StartsAt),
+		nullTime(mw.EndsAt),
+		mw.CronExpression,
+		mw.DurationMinutes,
+		mw.Active,
+		time.Now(),
+		mw.ID,
+	)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+func (m *postgresDBRepo) DeleteMaintenanceWindow(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from maintenance_windows where id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMaintenanceWindow scans a maintenance_windows row; null start and end times
+// (recurring windows) become zero times
+func scanMaintenanceWindow(row rowScanner) (models.MaintenanceWindow, error) {
+	var mw models.MaintenanceWindow
+	var startsAt, endsAt sql.NullTime
+
+	err := row.Scan(
+		&mw.ID,
+		&mw.Name,
+		&mw.Scope,
+		&mw.HostID,
+		&mw.HostServiceID,
+		&startsAt,
+		&endsAt,
+		&mw.CronExpression,
+		&mw.DurationMinutes,
+		&mw.Active,
+		&mw.CreatedAt,
+		&mw.UpdatedAt,
+	)
+	mw.StartsAt = startsAt.Time
+	mw.EndsAt = endsAt.Time
+	return mw, err
+}
+
+// nullTime stores the zero time as null
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
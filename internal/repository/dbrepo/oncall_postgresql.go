@@ -0,0 +1,249 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// AllOnCallSchedules returns all on-call schedules with their overrides, ordered by name
+func (m *postgresDBRepo) AllOnCallSchedules() ([]models.OnCallSchedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, time_zone, user_ids, rotation_start, shift_days, created_at, updated_at
+		from oncall_schedules
+		order by name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.OnCallSchedule
+	for rows.Next() {
+		var s models.OnCallSchedule
+		err := rows.Scan(
+			&s.ID,
+			&s.Name,
+			&s.TimeZone,
+			&s.UserIDs,
+			&s.RotationStart,
+			&s.ShiftDays,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	for i := range schedules {
+		schedules[i].Overrides, err = m.getOnCallOverrides(schedules[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return schedules, nil
+}
+
+// GetOnCallScheduleByID returns an on-call schedule with its overrides
+func (m *postgresDBRepo) GetOnCallScheduleByID(id int) (models.OnCallSchedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, time_zone, user_ids, rotation_start, shift_days, created_at, updated_at
+		from oncall_schedules
+		where id = $1
+	`
+
+	var s models.OnCallSchedule
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&s.ID,
+		&s.Name,
+		&s.TimeZone,
+		&s.UserIDs,
+		&s.RotationStart,
+		&s.ShiftDays,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		return s, err
+	}
+
+	s.Overrides, err = m.getOnCallOverrides(s.ID)
+	if err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// getOnCallOverrides returns the overrides of a schedule in the order they were added,
+// which is the order they are layered in
+func (m *postgresDBRepo) getOnCallOverrides(scheduleID int) ([]models.OnCallOverride, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select o.id, o.oncall_schedule_id, o.user_id, coalesce(u.first_name || ' ' || u.last_name, ''),
+			o.starts_at, o.ends_at, o.created_at, o.updated_at
+		from oncall_overrides o
+			left join users u on (u.id = o.user_id)
+		where o.oncall_schedule_id = $1
+		order by o.id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, scheduleID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []models.OnCallOverride
+	for rows.Next() {
+		var o models.OnCallOverride
+		err := rows.Scan(
+			&o.ID,
+			&o.OnCallScheduleID,
+			&o.UserID,
+			&o.UserName,
+			&o.StartsAt,
+			&o.EndsAt,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// InsertOnCallSchedule inserts an on-call schedule and returns its id
+func (m *postgresDBRepo) InsertOnCallSchedule(s models.OnCallSchedule) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into oncall_schedules (name, time_zone, user_ids, rotation_start, shift_days, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt,
+		s.Name,
+		s.TimeZone,
+		s.UserIDs,
+		s.RotationStart,
+		s.ShiftDays,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return newID, err
+	}
+	return newID, nil
+}
+
+// UpdateOnCallSchedule updates an on-call schedule; its overrides are left alone
+func (m *postgresDBRepo) UpdateOnCallSchedule(s models.OnCallSchedule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update oncall_schedules set name = $1, time_zone = $2, user_ids = $3, rotation_start = $4,
+			shift_days = $5, updated_at = $6
+		where id = $7
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		s.Name,
+		s.TimeZone,
+		s.UserIDs,
+		s.RotationStart,
+		s.ShiftDays,
+		time.Now(),
+		s.ID,
+	)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteOnCallSchedule deletes an on-call schedule and detaches it from hosts
+func (m *postgresDBRepo) DeleteOnCallSchedule(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `update hosts set oncall_schedule_id = 0 where oncall_schedule_id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, `delete from oncall_schedules where id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// InsertOnCallOverride adds an override to a schedule and returns its id
+func (m *postgresDBRepo) InsertOnCallOverride(o models.OnCallOverride) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into oncall_overrides (oncall_schedule_id, user_id, starts_at, ends_at, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt, o.OnCallScheduleID, o.UserID, o.StartsAt, o.EndsAt, time.Now(), time.Now()).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return newID, err
+	}
+	return newID, nil
+}
+
+// DeleteOnCallOverride deletes an override of a schedule
+func (m *postgresDBRepo) DeleteOnCallOverride(scheduleID, id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from oncall_overrides where id = $1 and oncall_schedule_id = $2`, id, scheduleID)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
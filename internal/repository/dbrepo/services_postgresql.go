@@ -0,0 +1,68 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// AllServices returns all services, ordered by name
+func (m *postgresDBRepo) AllServices() ([]models.Services, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, service_name, service_type, active, icon, created_at, updated_at
+		from services
+		order by service_name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []models.Services
+	for rows.Next() {
+		var s models.Services
+		err := rows.Scan(
+			&s.ID,
+			&s.ServiceName,
+			&s.ServiceType,
+			&s.Active,
+			&s.Icon,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		services = append(services, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return services, nil
+}
+
+// UpdateServiceActive activates or deactivates a service on every host
+func (m *postgresDBRepo) UpdateServiceActive(id, active int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `update services set active = $1, updated_at = $2 where id = $3`
+
+	_, err := m.DB.ExecContext(ctx, stmt, active, time.Now(), id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package dbrepo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// uptimeQuery adds up, per host service, the time each check result held between from
+// ($1) and to ($2), and the incidents recorded in events. A result holds until the next
+// one; the last result ever taken holds for no time at all, so a service whose checks
+// have stopped does not keep gathering uptime or downtime. An incident is a change to
+// problem, and it is recovered by the next status change. The %s are filters on
+// check_results and events.
+const uptimeQuery = `
+	with results as (
+		select r.host_service_id, r.status, r.maintenance, r.checked_at,
+			coalesce(
+				lead(r.checked_at) over (partition by r.host_service_id order by r.checked_at),
+				(select min(n.checked_at) from check_results n
+					where n.host_service_id = r.host_service_id and n.checked_at >= $2),
+				r.checked_at
+			) as next_at
+		from check_results r
+		where r.checked_at >= $1 and r.checked_at < $2 %s
+	),
+	held as (
+		select host_service_id,
+			coalesce(sum(extract(epoch from least(next_at, $2) - checked_at))
+				filter (where maintenance <> 1), 0)::float8 as observed,
+			coalesce(sum(extract(epoch from least(next_at, $2) - checked_at))
+				filter (where maintenance = 1), 0)::float8 as maintenance,
+			coalesce(sum(extract(epoch from least(next_at, $2) - checked_at))
+				filter (where maintenance <> 1 and status = 'problem'), 0)::float8 as downtime
+		from results
+		group by host_service_id
+	),
+	incidents as (
+		select host_service_id,
+			count(*) as incidents,
+			count(recovered_at) as recovered,
+			coalesce(sum(extract(epoch from recovered_at - created_at)), 0)::float8 as time_to_recover
+		from (
+			select e.host_service_id, e.event_type, e.maintenance, e.created_at,
+				lead(e.created_at) over (partition by e.host_service_id order by e.created_at) as recovered_at
+			from events e
+			where e.created_at >= $1 and e.created_at < $2
+				and e.event_type in ('healthy', 'warning', 'problem', 'unreachable') %s
+		) s
+		where event_type = 'problem' and maintenance <> 1
+		group by host_service_id
+	)
+	select h.host_service_id, h.observed, h.maintenance, h.downtime,
+		coalesce(i.incidents, 0), coalesce(i.recovered, 0), coalesce(i.time_to_recover, 0)
+	from held h
+	left join incidents i on i.host_service_id = h.host_service_id
+	order by h.host_service_id
+`
+
+// GetUptimeTotals returns the uptime totals of every host service between from and to
+func (m *postgresDBRepo) GetUptimeTotals(from, to time.Time) ([]models.UptimeTotal, error) {
+	query := fmt.Sprintf(uptimeQuery, "", "")
+	return m.queryUptimeTotals(query, from, to)
+}
+
+// GetUptimeTotalsForHost returns the uptime totals of the services on a host between
+// from and to
+func (m *postgresDBRepo) GetUptimeTotalsForHost(hostID int, from, to time.Time) ([]models.UptimeTotal, error) {
+	query := fmt.Sprintf(uptimeQuery, "and r.host_id = $3", "and e.host_id = $3")
+	return m.queryUptimeTotals(query, from, to, hostID)
+}
+
+// queryUptimeTotals runs an uptime query and scans the rows
+func (m *postgresDBRepo) queryUptimeTotals(query string, args ...interface{}) ([]models.UptimeTotal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	seconds := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second))
+	}
+
+	var totals []models.UptimeTotal
+	for rows.Next() {
+		var t models.UptimeTotal
+		var observed, maintenance, downtime, timeToRecover float64
+		err := rows.Scan(
+			&t.HostServiceID,
+			&observed,
+			&maintenance,
+			&downtime,
+			&t.Incidents,
+			&t.Recovered,
+			&timeToRecover,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		t.Observed = seconds(observed)
+		t.Maintenance = seconds(maintenance)
+		t.Downtime = seconds(downtime)
+		t.TimeToRecover = seconds(timeToRecover)
+		totals = append(totals, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return totals, nil
+}
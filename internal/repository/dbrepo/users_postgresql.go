@@ -15,7 +15,7 @@ func (m *postgresDBRepo) AllUsers() ([]*models.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	stmt := `SELECT id, last_name, first_name, email, user_active, created_at, updated_at FROM users
+	stmt := `SELECT id, last_name, first_name, email, phone, user_active, created_at, updated_at FROM users
 		where deleted_at is null`
 
 	rows, err := m.DB.QueryContext(ctx, stmt)
@@ -28,7 +28,7 @@ func (m *postgresDBRepo) AllUsers() ([]*models.User, error) {
 
 	for rows.Next() {
 		s := &models.User{}
-		err = rows.Scan(&s.ID, &s.LastName, &s.FirstName, &s.Email, &s.UserActive, &s.CreatedAt, &s.UpdatedAt)
+		err = rows.Scan(&s.ID, &s.LastName, &s.FirstName, &s.Email, &s.Phone, &s.UserActive, &s.CreatedAt, &s.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -49,7 +49,7 @@ func (m *postgresDBRepo) GetUserById(id int) (models.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	stmt := `SELECT id, first_name, last_name,  user_active, access_level, email, 
+	stmt := `SELECT id, first_name, last_name,  user_active, access_level, email, phone,
 			created_at, updated_at
 			FROM users where id = $1`
 	row := m.DB.QueryRowContext(ctx, stmt, id)
@@ -63,6 +63,7 @@ func (m *postgresDBRepo) GetUserById(id int) (models.User, error) {
 		&u.UserActive,
 		&u.AccessLevel,
 		&u.Email,
+		&u.Phone,
 		&u.CreatedAt,
 		&u.UpdatedAt,
 	)
@@ -175,9 +176,10 @@ func (m *postgresDBRepo) InsertUser(u models.User) (int, error) {
 		email, 
 		password, 
 		access_level,
-		user_active
+		user_active,
+		phone
 		)
-    VALUES($1, $2, $3, $4, $5, $6) returning id `
+    VALUES($1, $2, $3, $4, $5, $6, $7) returning id `
 
 	var newId int
 	err = m.DB.QueryRowContext(ctx, stmt,
@@ -186,7 +188,8 @@ func (m *postgresDBRepo) InsertUser(u models.User) (int, error) {
 		u.Email,
 		hashedPassword,
 		u.AccessLevel,
-		&u.UserActive).Scan(&newId)
+		&u.UserActive,
+		u.Phone).Scan(&newId)
 	if err != nil {
 		return 0, err
 	}
@@ -208,9 +211,10 @@ func (m *postgresDBRepo) UpdateUser(u models.User) error {
 			user_active = $3, 
 			email = $4, 
 			access_level = $5,
-			updated_at = $6
+			updated_at = $6,
+			phone = $7
 		where
-			id = $7`
+			id = $8`
 
 	_, err := m.DB.ExecContext(ctx, stmt,
 		u.FirstName,
@@ -219,6 +223,7 @@ func (m *postgresDBRepo) UpdateUser(u models.User) error {
 		u.Email,
 		u.AccessLevel,
 		u.UpdatedAt,
+		u.Phone,
 		u.ID,
 	)
 	if err != nil {
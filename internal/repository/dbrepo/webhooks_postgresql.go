@@ -0,0 +1,266 @@
+package dbrepo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+// AllWebhooks returns all webhooks, ordered by name
+func (m *postgresDBRepo) AllWebhooks() ([]models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, url, body_template, headers, secret, active, created_at, updated_at
+		from webhooks
+		order by name
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var wh models.Webhook
+		err := rows.Scan(
+			&wh.ID,
+			&wh.Name,
+			&wh.URL,
+			&wh.BodyTemplate,
+			&wh.Headers,
+			&wh.Secret,
+			&wh.Active,
+			&wh.CreatedAt,
+			&wh.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhookByID returns a webhook by id
+func (m *postgresDBRepo) GetWebhookByID(id int) (models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select id, name, url, body_template, headers, secret, active, created_at, updated_at
+		from webhooks
+		where id = $1
+	`
+
+	var wh models.Webhook
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&wh.ID,
+		&wh.Name,
+		&wh.URL,
+		&wh.BodyTemplate,
+		&wh.Headers,
+		&wh.Secret,
+		&wh.Active,
+		&wh.CreatedAt,
+		&wh.UpdatedAt,
+	)
+	if err != nil {
+		log.Println(err)
+		return wh, err
+	}
+	return wh, nil
+}
+
+// InsertWebhook inserts a webhook and returns its id
+func (m *postgresDBRepo) InsertWebhook(wh models.Webhook) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into webhooks (name, url, body_template, headers, secret, active, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt,
+		wh.Name,
+		wh.URL,
+		wh.BodyTemplate,
+		wh.Headers,
+		wh.Secret,
+		wh.Active,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+	return newID, nil
+}
+
+// UpdateWebhook updates a webhook by id
+func (m *postgresDBRepo) UpdateWebhook(wh models.Webhook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update webhooks set name = $1, url = $2, body_template = $3, headers = $4, secret = $5,
+			active = $6, updated_at = $7
+		where id = $8
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		wh.Name,
+		wh.URL,
+		wh.BodyTemplate,
+		wh.Headers,
+		wh.Secret,
+		wh.Active,
+		time.Now(),
+		wh.ID,
+	)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteWebhook deletes a webhook and, through the foreign key, its deliveries
+func (m *postgresDBRepo) DeleteWebhook(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `delete from webhooks where id = $1`, id)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// InsertWebhookDelivery starts the log of a webhook delivery and returns its id
+func (m *postgresDBRepo) InsertWebhookDelivery(d models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		insert into webhook_deliveries (webhook_id, host_service_id, event, status, attempts, response_code,
+			response_body, error, payload, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id
+	`
+
+	var newID int
+	err := m.DB.QueryRowContext(ctx, stmt,
+		d.WebhookID,
+		d.HostServiceID,
+		d.Event,
+		d.Status,
+		d.Attempts,
+		d.ResponseCode,
+		d.ResponseBody,
+		d.Error,
+		d.Payload,
+		time.Now(),
+		time.Now(),
+	).Scan(&newID)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+	return newID, nil
+}
+
+// UpdateWebhookDelivery records the outcome of the latest attempt of a delivery
+func (m *postgresDBRepo) UpdateWebhookDelivery(d models.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `
+		update webhook_deliveries set status = $1, attempts = $2, response_code = $3, response_body = $4,
+			error = $5, updated_at = $6
+		where id = $7
+	`
+
+	_, err := m.DB.ExecContext(ctx, stmt,
+		d.Status,
+		d.Attempts,
+		d.ResponseCode,
+		d.ResponseBody,
+		d.Error,
+		time.Now(),
+		d.ID,
+	)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// GetRecentWebhookDeliveries returns the latest webhook deliveries, newest first
+func (m *postgresDBRepo) GetRecentWebhookDeliveries(limit int) ([]models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+		select d.id, d.webhook_id, w.name, d.host_service_id, d.event, d.status, d.attempts, d.response_code,
+			d.response_body, d.error, d.payload, d.created_at, d.updated_at
+		from webhook_deliveries d
+			left join webhooks w on (w.id = d.webhook_id)
+		order by d.created_at desc
+		limit $1
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.WebhookName,
+			&d.HostServiceID,
+			&d.Event,
+			&d.Status,
+			&d.Attempts,
+			&d.ResponseCode,
+			&d.ResponseBody,
+			&d.Error,
+			&d.Payload,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -1,6 +1,10 @@
 package repository
 
-import "github.com/brianmaksy/go-watch/internal/models"
+import (
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
 
 // DatabaseRepo is the database repository
 type DatabaseRepo interface {
@@ -32,8 +36,82 @@ type DatabaseRepo interface {
 	GetServicesByStatus(status string) ([]models.HostService, error)
 	GetHostServiceByID(id int) (models.HostService, error)
 	UpdateHostService(hs models.HostService) error
+	UpdateHostServiceConfig(hs models.HostService) error
+	UpdateHostServiceCheckState(hs models.HostService) error
 	GetServicesToMonitor() ([]models.HostService, error)
 	GetHostServiceByHostIDServiceID(hostID, serviceID int) (models.HostService, error)
+	AllServices() ([]models.Services, error)
+	UpdateServiceActive(id, active int) error
 	InsertEvent(e models.Event) error
 	GetAllEvents() ([]models.Event, error)
+
+	// check results
+	InsertCheckResult(cr models.CheckResult) error
+	GetCheckResultsForHostService(hostServiceID int, from, to time.Time) ([]models.CheckResult, error)
+	GetCheckResultsForHost(hostID int, from, to time.Time) ([]models.CheckResult, error)
+	GetAllCheckResults(from, to time.Time) ([]models.CheckResult, error)
+	DeleteCheckResultsBefore(t time.Time) (int64, error)
+	CountStatusChanges(hostServiceID int, from, to time.Time) (int, error)
+	GetUptimeTotals(from, to time.Time) ([]models.UptimeTotal, error)
+	GetUptimeTotalsForHost(hostID int, from, to time.Time) ([]models.UptimeTotal, error)
+
+	// webhooks
+	AllWebhooks() ([]models.Webhook, error)
+	GetWebhookByID(id int) (models.Webhook, error)
+	InsertWebhook(wh models.Webhook) (int, error)
+	UpdateWebhook(wh models.Webhook) error
+	DeleteWebhook(id int) error
+	InsertWebhookDelivery(d models.WebhookDelivery) (int, error)
+	UpdateWebhookDelivery(d models.WebhookDelivery) error
+	GetRecentWebhookDeliveries(limit int) ([]models.WebhookDelivery, error)
+
+	// maintenance windows
+	AllMaintenanceWindows() ([]models.MaintenanceWindow, error)
+	GetMaintenanceWindowByID(id int) (models.MaintenanceWindow, error)
+	InsertMaintenanceWindow(mw models.MaintenanceWindow) (int, error)
+	UpdateMaintenanceWindow(mw models.MaintenanceWindow) error
+	DeleteMaintenanceWindow(id int) error
+
+	// host dependencies
+	AllHostDependencies() ([]models.HostDependency, error)
+	GetDependenciesForHost(hostID int) ([]models.HostDependency, error)
+	InsertHostDependency(d models.HostDependency) (int, error)
+	DeleteHostDependency(id int) error
+	GetDownParent(hostID, hostServiceID int) (string, error)
+
+	// incidents
+	AllIncidents(status string) ([]models.Incident, error)
+	GetIncidentByID(id int) (models.Incident, error)
+	GetOpenIncidentForHostService(hostServiceID int) (models.Incident, error)
+	InsertIncident(i models.Incident) (int, error)
+	AcknowledgeIncident(id, userID int) (bool, error)
+	ResolveIncident(id int) error
+	UpdateIncidentResolution(id int, resolution string) error
+	InsertIncidentNote(n models.IncidentNote) (int, error)
+	GetIncidentNotes(incidentID int) ([]models.IncidentNote, error)
+	GetEventsForIncident(incidentID int) ([]models.Event, error)
+	GetCheckResultsForIncident(incidentID int) ([]models.CheckResult, error)
+	LinkCheckResultsToIncident(incidentID, hostServiceID int, from time.Time) error
+	GetIncidentsByStatus(status string) ([]models.Incident, error)
+	UpdateIncidentEscalation(id, step int, at time.Time) error
+
+	// escalation policies
+	AllEscalationPolicies() ([]models.EscalationPolicy, error)
+	GetEscalationPolicyByID(id int) (models.EscalationPolicy, error)
+	InsertEscalationPolicy(p models.EscalationPolicy) (int, error)
+	UpdateEscalationPolicy(p models.EscalationPolicy) error
+	DeleteEscalationPolicy(id int) error
+
+	// on-call schedules
+	AllOnCallSchedules() ([]models.OnCallSchedule, error)
+	GetOnCallScheduleByID(id int) (models.OnCallSchedule, error)
+	InsertOnCallSchedule(s models.OnCallSchedule) (int, error)
+	UpdateOnCallSchedule(s models.OnCallSchedule) error
+	DeleteOnCallSchedule(id int) error
+	InsertOnCallOverride(o models.OnCallOverride) (int, error)
+	DeleteOnCallOverride(scheduleID, id int) error
+
+	// leases
+	AcquireLease(name, holder string, ttl time.Duration) (models.Lease, error)
+	ReleaseLease(name, holder string) error
 }
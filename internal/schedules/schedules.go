@@ -0,0 +1,154 @@
+package schedules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// Units an interval schedule can be given in
+var Units = []string{"s", "m", "h", "d"}
+
+// cronParser reads the five-field cron expressions a host service can be scheduled on
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Cron reports whether a host service is checked on a cron expression rather than at
+// an interval
+func Cron(hs models.HostService) bool {
+	return strings.TrimSpace(hs.ScheduleCron) != ""
+}
+
+// Spec returns the spec the scheduler runs a host service's checks on. The scheduler
+// has no day unit, so days are given in hours.
+func Spec(hs models.HostService) string {
+	if Cron(hs) {
+		if hs.ScheduleTimeZone != "" {
+			return fmt.Sprintf("CRON_TZ=%s %s", hs.ScheduleTimeZone, strings.TrimSpace(hs.ScheduleCron))
+		}
+		return strings.TrimSpace(hs.ScheduleCron)
+	}
+	if hs.ScheduleUnit == "d" {
+		return fmt.Sprintf("@every %dh", hs.ScheduleNumber*24)
+	}
+	return fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit)
+}
+
+// Describe returns a host service's schedule the way it is entered on the host page
+func Describe(hs models.HostService) string {
+	if Cron(hs) {
+		if hs.ScheduleTimeZone != "" {
+			return fmt.Sprintf("%s (%s)", strings.TrimSpace(hs.ScheduleCron), hs.ScheduleTimeZone)
+		}
+		return strings.TrimSpace(hs.ScheduleCron)
+	}
+	return fmt.Sprintf("@every %d%s", hs.ScheduleNumber, hs.ScheduleUnit)
+}
+
+// Paused returns why a host service is not checked: its host is inactive, or its
+// service has been deactivated on every host. It is empty if the host service is not
+// paused; whether it is active on its host is up to the caller.
+func Paused(h models.Host, hs models.HostService) string {
+	switch {
+	case h.Active != 1:
+		return "host is inactive"
+	case hs.Service.Active != 1:
+		return "service is deactivated"
+	}
+	return ""
+}
+
+// Validate returns an error describing the first problem with a host service's schedule
+func Validate(hs models.HostService) error {
+	if Cron(hs) {
+		if _, err := location(hs); err != nil {
+			return fmt.Errorf("unknown time zone '%s'", hs.ScheduleTimeZone)
+		}
+		if strings.Contains(hs.ScheduleCron, "TZ=") {
+			return errors.New("set the time zone in its own field, not in the cron expression")
+		}
+		if _, err := cronParser.Parse(strings.TrimSpace(hs.ScheduleCron)); err != nil {
+			return fmt.Errorf("invalid cron expression: %s", err)
+		}
+		return nil
+	}
+
+	if hs.ScheduleNumber < 1 {
+		return errors.New("the interval must be at least 1")
+	}
+	for _, u := range Units {
+		if hs.ScheduleUnit == u {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown interval unit '%s'", hs.ScheduleUnit)
+}
+
+// NextRuns returns the next n times a host service is due to be checked after from.
+// Cron schedules with a time zone give their times in that zone.
+func NextRuns(hs models.HostService, from time.Time, n int) ([]time.Time, error) {
+	if err := Validate(hs); err != nil {
+		return nil, err
+	}
+
+	sched, err := cron.ParseStandard(Spec(hs))
+	if err != nil {
+		return nil, err
+	}
+	loc, err := location(hs)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []time.Time
+	t := from
+	for len(runs) < n {
+		t = sched.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t.In(loc))
+	}
+	return runs, nil
+}
+
+// NextRunsFrom returns the next n times a host service is due to be checked, given
+// that the scheduler will next run it at next. An interval counts from when the
+// scheduler started it, not from now, so later runs are stepped on from next.
+func NextRunsFrom(hs models.HostService, next time.Time, n int) ([]time.Time, error) {
+	if n < 1 {
+		return nil, nil
+	}
+	loc, err := location(hs)
+	if err != nil {
+		return nil, err
+	}
+
+	later, err := NextRuns(hs, next, n-1)
+	if err != nil {
+		return nil, err
+	}
+	return append([]time.Time{next.In(loc)}, later...), nil
+}
+
+// Period returns how long there is between checks of a host service, or 0 if its
+// schedule is invalid. For a cron expression it is the time between its next two runs
+// after from.
+func Period(hs models.HostService, from time.Time) time.Duration {
+	runs, err := NextRuns(hs, from, 2)
+	if err != nil || len(runs) < 2 {
+		return 0
+	}
+	return runs[1].Sub(runs[0])
+}
+
+// location returns the time zone a host service's cron expression is read in
+func location(hs models.HostService) (*time.Location, error) {
+	if !Cron(hs) || hs.ScheduleTimeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(hs.ScheduleTimeZone)
+}
@@ -0,0 +1,38 @@
+package schedules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brianmaksy/go-watch/internal/models"
+)
+
+func TestNextRunsFrom(t *testing.T) {
+	next := time.Date(2026, 10, 18, 12, 0, 7, 0, time.Local)
+
+	runs, err := NextRunsFrom(models.HostService{ScheduleNumber: 3, ScheduleUnit: "m"}, next, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{next, next.Add(3 * time.Minute), next.Add(6 * time.Minute)}
+	if len(runs) != len(want) {
+		t.Fatalf("got %d runs, want %d", len(runs), len(want))
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("run %d is %s, want %s", i, runs[i], want[i])
+		}
+	}
+
+	cronRuns, err := NextRunsFrom(models.HostService{ScheduleCron: "0 * * * *", ScheduleTimeZone: "UTC"}, time.Date(2026, 10, 18, 13, 0, 0, 0, time.UTC), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cronRuns) != 2 || cronRuns[1].Hour() != 14 || cronRuns[1].Location() != time.UTC {
+		t.Errorf("got %v, want 13:00 and 14:00 UTC", cronRuns)
+	}
+
+	if _, err := NextRunsFrom(models.HostService{ScheduleNumber: 0, ScheduleUnit: "m"}, next, 3); err == nil {
+		t.Error("invalid schedule did not fail")
+	}
+}
@@ -14,4 +14,6 @@ type TemplateData struct {
 	Warning         string
 	Error           string
 	GwVersion       string
+	Instance        string // name of this instance
+	Leader          string // name of the instance running checks, if known
 }